@@ -0,0 +1,75 @@
+package ordered
+
+// Backing selects the data structure that holds the order of a Map. Each
+// backing makes a different trade-off between positional access and deletion.
+type Backing int
+
+const (
+	// Tree holds the order in a balanced tree indexed by position. Index,
+	// IndexOf, deleting any key and inserting at any index are O(log n).
+	// This is the default, as the only backing on which positional access
+	// and deleting any key are both sub-linear.
+	Tree Backing = iota
+	// List holds the order in a doubly linked list. Deleting any key is
+	// O(1), Index is O(min(i, n-i)) and IndexOf is O(i).
+	List
+	// Deque holds the order in a ring buffer. Index, IndexOf, Store,
+	// StoreFirst, LoadAndDeleteFirst and LoadAndDeleteLast are amortised
+	// O(1), deleting any other key is O(min(i, n-i)) for the key at index i.
+	Deque
+)
+
+// backing holds the order of the entries in a Map. Indices passed to a backing
+// are always in range.
+type backing[K comparable, V any] interface {
+	// len returns the number of entries.
+	len() int
+	// at returns the entry at index n.
+	at(n int) *entry[K, V]
+	// index returns the index of e.
+	index(e *entry[K, V]) int
+	// insert inserts e at index n, n may be equal to len.
+	insert(n int, e *entry[K, V])
+	// remove removes e.
+	remove(e *entry[K, V])
+	// removeRange removes the entries from index i up to but not including
+	// index j.
+	removeRange(i, j int)
+	// next returns the entry after e, or nil if e is the last entry.
+	next(e *entry[K, V]) *entry[K, V]
+	// prev returns the entry before e, or nil if e is the first entry.
+	prev(e *entry[K, V]) *entry[K, V]
+	// grow makes room for at least n more entries.
+	grow(n int)
+	// compact releases any spare capacity.
+	compact()
+}
+
+func newBacking[K comparable, V any](b Backing) backing[K, V] {
+	switch b {
+	case List:
+		return newList[K, V]()
+	case Deque:
+		return &deque[K, V]{}
+	default:
+		return &tree[K, V]{seed: 1}
+	}
+}
+
+// entry is a key and value held in the order of a Map. The remaining fields
+// are owned by the backing.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+
+	// prev and next link the entry into a list, in a tree they are the
+	// entry's left and right children.
+	prev, next *entry[K, V]
+	// parent is the entry's parent in a tree.
+	parent *entry[K, V]
+	// pos is the entry's slot in a deque, or the size of its subtree in a
+	// tree.
+	pos int
+	// prio is the entry's heap priority in a tree.
+	prio uint32
+}
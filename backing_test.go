@@ -0,0 +1,107 @@
+package ordered
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var backings = map[string]Backing{
+	"deque": Deque,
+	"list":  List,
+	"tree":  Tree,
+}
+
+// TestBackings compares random operations on each backing with a slice.
+func TestBackings(t *testing.T) {
+	for name, b := range backings {
+		t.Run(name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			m := NewMap[int, int](WithBacking(b))
+			var want []int
+			for i := 0; i < 5000; i++ {
+				switch op := r.Intn(7); {
+				case op < 2 || len(want) == 0:
+					n := r.Intn(len(want) + 1)
+					m.mu.Lock()
+					m.local.insert(n, &entry[int, int]{key: i, value: i})
+					m.mu.Unlock()
+					want = append(want[:n], append([]int{i}, want[n:]...)...)
+				case op < 4:
+					n := r.Intn(len(want))
+					key := want[n]
+					if _, loaded := m.LoadAndDelete(key); !loaded {
+						t.Fatalf("Key %d at index %d not loaded", key, n)
+					}
+					want = append(want[:n], want[n+1:]...)
+				case op < 5:
+					i, j := r.Intn(len(want)), r.Intn(len(want))
+					m.Swap(i, j)
+					want[i], want[j] = want[j], want[i]
+				case op < 6:
+					i := r.Intn(len(want))
+					j := i + r.Intn(min(len(want)-i, 5)+1)
+					if deleted := m.DeleteRange(i, j); deleted != j-i {
+						t.Fatalf("Unexpected deleted from %d to %d, wanted %d but got %d", i, j, j-i, deleted)
+					}
+					want = append(want[:i], want[j:]...)
+				default:
+					n := r.Intn(len(want))
+					if key, _, _ := m.Index(n); key != want[n] {
+						t.Fatalf("Unexpected key at index %d, wanted %d but got %d", n, want[n], key)
+					}
+					if got := m.local.order.index(m.local.dirty[want[n]]); got != n {
+						t.Fatalf("Unexpected index of key %d, wanted %d but got %d", want[n], n, got)
+					}
+					if n > 0 {
+						if key, _, _ := m.Prev(want[n]); key != want[n-1] {
+							t.Fatalf("Unexpected key before %d, wanted %d but got %d", want[n], want[n-1], key)
+						}
+					}
+					if n < len(want)-1 {
+						if key, _, _ := m.Next(want[n]); key != want[n+1] {
+							t.Fatalf("Unexpected key after %d, wanted %d but got %d", want[n], want[n+1], key)
+						}
+					}
+				}
+			}
+
+			order, _ := content(&m.local)
+			if !reflect.DeepEqual(order, want) {
+				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", order, want)
+			}
+			if m.Len() != len(want) {
+				t.Errorf("Unexpected length, wanted %d but got %d", len(want), m.Len())
+			}
+		})
+	}
+}
+
+func BenchmarkStoreFirst(b *testing.B) {
+	for name, backing := range backings {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m := NewMap[int, int](WithBacking(backing))
+				for i := 0; i < 100000; i++ {
+					m.StoreFirst(i, i)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDeleteRange(b *testing.B) {
+	for name, backing := range backings {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				m := NewMap[int, int](WithBacking(backing))
+				for i := 0; i < 100000; i++ {
+					m.Store(i, i)
+				}
+				b.StartTimer()
+				m.DeleteRange(40000, 60000)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ordered
+
+import "math/bits"
+
+// deque is a ring buffer backing. The entry at index i is held in
+// buf[(head+i)&(len(buf)-1)] and records that slot in its pos field. The
+// length of buf is always zero or a power of two.
+type deque[K comparable, V any] struct {
+	buf  []*entry[K, V]
+	head int
+	n    int
+}
+
+func (d *deque[K, V]) len() int {
+	return d.n
+}
+
+// slot returns the slot in buf of index i.
+func (d *deque[K, V]) slot(i int) int {
+	return (d.head + i) & (len(d.buf) - 1)
+}
+
+// index returns the index of e.
+func (d *deque[K, V]) index(e *entry[K, V]) int {
+	return (e.pos - d.head) & (len(d.buf) - 1)
+}
+
+// set puts e at index i.
+func (d *deque[K, V]) set(i int, e *entry[K, V]) {
+	e.pos = d.slot(i)
+	d.buf[e.pos] = e
+}
+
+func (d *deque[K, V]) at(n int) *entry[K, V] {
+	return d.buf[d.slot(n)]
+}
+
+// grow makes room for at least n more entries.
+func (d *deque[K, V]) grow(n int) {
+	if d.n+n > len(d.buf) {
+		d.resize(d.n + n)
+	}
+}
+
+// compact shrinks buf to the smallest power of two that holds the entries.
+func (d *deque[K, V]) compact() {
+	d.resize(d.n)
+}
+
+// resize replaces buf with one that holds at least n entries, unwrapping the
+// entries to start at slot 0.
+func (d *deque[K, V]) resize(n int) {
+	size := 0
+	if n > 0 {
+		size = 1 << bits.Len(uint(n-1))
+	}
+	if size == len(d.buf) && d.head == 0 {
+		return
+	}
+
+	var buf []*entry[K, V]
+	if size > 0 {
+		buf = make([]*entry[K, V], size)
+	}
+	for i := 0; i < d.n; i++ {
+		e := d.at(i)
+		e.pos = i
+		buf[i] = e
+	}
+	d.buf = buf
+	d.head = 0
+}
+
+// insert makes room at index n by moving whichever side of n is shorter.
+func (d *deque[K, V]) insert(n int, e *entry[K, V]) {
+	if d.n == len(d.buf) {
+		d.resize(max(2*d.n, 8))
+	}
+
+	if n < d.n/2 {
+		d.head = (d.head - 1) & (len(d.buf) - 1)
+		for i := 0; i < n; i++ {
+			d.set(i, d.at(i+1))
+		}
+	} else {
+		for i := d.n; i > n; i-- {
+			d.set(i, d.at(i-1))
+		}
+	}
+	d.set(n, e)
+	d.n++
+}
+
+// remove closes the gap at e by moving whichever side of it is shorter. The
+// vacated slot is cleared so the entry can be garbage collected.
+func (d *deque[K, V]) remove(e *entry[K, V]) {
+	n := d.index(e)
+	if n < d.n/2 {
+		for i := n; i > 0; i-- {
+			d.set(i, d.at(i-1))
+		}
+		d.buf[d.head] = nil
+		d.head = (d.head + 1) & (len(d.buf) - 1)
+	} else {
+		for i := n; i < d.n-1; i++ {
+			d.set(i, d.at(i+1))
+		}
+		d.buf[d.slot(d.n-1)] = nil
+	}
+	d.n--
+}
+
+// removeRange closes the gap from i to j by moving whichever side of it is
+// shorter once. The vacated slots are cleared so the entries can be garbage
+// collected.
+func (d *deque[K, V]) removeRange(i, j int) {
+	k := j - i
+	if i < d.n-j {
+		for n := i - 1; n >= 0; n-- {
+			d.set(n+k, d.at(n))
+		}
+		for n := 0; n < k; n++ {
+			d.buf[d.slot(n)] = nil
+		}
+		d.head = (d.head + k) & (len(d.buf) - 1)
+	} else {
+		for n := j; n < d.n; n++ {
+			d.set(n-k, d.at(n))
+		}
+		for n := d.n - k; n < d.n; n++ {
+			d.buf[d.slot(n)] = nil
+		}
+	}
+	d.n -= k
+}
+
+func (d *deque[K, V]) next(e *entry[K, V]) *entry[K, V] {
+	n := d.index(e) + 1
+	if n >= d.n {
+		return nil
+	}
+	return d.at(n)
+}
+
+func (d *deque[K, V]) prev(e *entry[K, V]) *entry[K, V] {
+	n := d.index(e) - 1
+	if n < 0 {
+		return nil
+	}
+	return d.at(n)
+}
@@ -0,0 +1,95 @@
+package ordered
+
+// list is a doubly linked list backing. The root entry is a sentinel,
+// root.next is the first entry and root.prev is the last.
+type list[K comparable, V any] struct {
+	root entry[K, V]
+	n    int
+}
+
+func newList[K comparable, V any]() *list[K, V] {
+	l := &list[K, V]{}
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	return l
+}
+
+func (l *list[K, V]) len() int {
+	return l.n
+}
+
+// at walks from whichever end of the list is closest to n.
+func (l *list[K, V]) at(n int) *entry[K, V] {
+	if n < l.n/2 {
+		e := l.root.next
+		for ; n > 0; n-- {
+			e = e.next
+		}
+		return e
+	}
+	e := l.root.prev
+	for n = l.n - 1 - n; n > 0; n-- {
+		e = e.prev
+	}
+	return e
+}
+
+// index counts the entries before e.
+func (l *list[K, V]) index(e *entry[K, V]) int {
+	n := 0
+	for ; e.prev != &l.root; e = e.prev {
+		n++
+	}
+	return n
+}
+
+func (l *list[K, V]) insert(n int, e *entry[K, V]) {
+	at := &l.root
+	if n < l.n {
+		at = l.at(n)
+	}
+
+	e.next = at
+	e.prev = at.prev
+	e.prev.next = e
+	e.next.prev = e
+	l.n++
+}
+
+func (l *list[K, V]) remove(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.next = nil
+	e.prev = nil
+	l.n--
+}
+
+// removeRange finds the entry at i once and unlinks the entries from there.
+func (l *list[K, V]) removeRange(i, j int) {
+	e := l.at(i)
+	for n := i; n < j; n++ {
+		next := e.next
+		l.remove(e)
+		e = next
+	}
+}
+
+// grow does nothing, a list allocates each entry as it is inserted.
+func (l *list[K, V]) grow(int) {}
+
+// compact does nothing, a list holds no spare capacity.
+func (l *list[K, V]) compact() {}
+
+func (l *list[K, V]) next(e *entry[K, V]) *entry[K, V] {
+	if e.next == &l.root {
+		return nil
+	}
+	return e.next
+}
+
+func (l *list[K, V]) prev(e *entry[K, V]) *entry[K, V] {
+	if e.prev == &l.root {
+		return nil
+	}
+	return e.prev
+}
@@ -0,0 +1,1021 @@
+package ordered
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// LocalMap is an ordered map data structure with the same methods as Map but
+// without any locking. It is intended for maps confined to a single goroutine,
+// it is not safe for concurrent use.
+//
+// The zero LocalMap is empty and ready for use, it uses the Tree backing. A
+// LocalMap must not be copied after first use.
+type LocalMap[K comparable, V any] struct {
+	order backing[K, V]
+	dirty map[K]*entry[K, V]
+	opts  options
+
+	// gen is incremented whenever an entry is inserted or removed, so that
+	// Range can tell if the order changed during a call to f.
+	gen uint64
+	// room is the number of keys dirty was last made with room for, so that
+	// Grow only rebuilds it when it is too small.
+	room int
+}
+
+// NewLocalMap returns an empty LocalMap configured by opts.
+func NewLocalMap[K comparable, V any](opts ...Option) *LocalMap[K, V] {
+	l := &LocalMap[K, V]{}
+	l.init(opts)
+	return l
+}
+
+func (l *LocalMap[K, V]) init(opts []Option) {
+	for _, opt := range opts {
+		opt(&l.opts)
+	}
+	l.lazyInit()
+}
+
+// Cloner is implemented by values which can copy themselves. Clone uses it to
+// copy the values of a map.
+type Cloner[V any] interface {
+	Clone() V
+}
+
+// cloneValue copies v using its Clone method if it implements Cloner.
+func cloneValue[V any](v V) V {
+	if c, ok := any(v).(Cloner[V]); ok {
+		return c.Clone()
+	}
+	return v
+}
+
+// Clear deletes all of the keys, the LocalMap keeps its options.
+func (l *LocalMap[K, V]) Clear() {
+	l.order = nil
+	l.dirty = nil
+	l.gen++
+}
+
+// Clone returns an independent copy of l with the same order and options.
+// Values which implement Cloner are copied with their Clone method, other
+// values are copied by assignment.
+func (l *LocalMap[K, V]) Clone() *LocalMap[K, V] {
+	return l.CloneFunc(cloneValue[V])
+}
+
+// CloneFunc returns an independent copy of l with the same order and options,
+// each value is copied by calling f.
+func (l *LocalMap[K, V]) CloneFunc(f func(V) V) *LocalMap[K, V] {
+	c := &LocalMap[K, V]{}
+	l.clone(c, f)
+	return c
+}
+
+// clone copies the contents of l into the empty LocalMap c using f to copy
+// each value.
+func (l *LocalMap[K, V]) clone(c *LocalMap[K, V], f func(V) V) {
+	c.opts = l.opts
+	c.lazyInit()
+	c.Grow(l.len())
+	l.each(func(e *entry[K, V]) {
+		c.insert(c.len(), &entry[K, V]{key: e.key, value: f(e.value)})
+	})
+}
+
+// Compact releases the memory held for deleted keys. The order is shrunk to
+// fit the current keys and the index of keys is rebuilt, Compact is O(n).
+func (l *LocalMap[K, V]) Compact() {
+	if l.order == nil {
+		return
+	}
+
+	l.order.compact()
+	l.room = len(l.dirty)
+	dirty := make(map[K]*entry[K, V], l.room)
+	for key, e := range l.dirty {
+		dirty[key] = e
+	}
+	l.dirty = dirty
+}
+
+// CompareAndDelete deletes the key if its value is equal to old. The old value
+// must be of a comparable type.
+//
+// If there is no current value for key in the LocalMap, CompareAndDelete
+// returns false (even if the old value is the nil interface value).
+func (l *LocalMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	e, ok := l.dirty[key]
+	if !ok || any(e.value) != any(old) {
+		return false
+	}
+
+	l.remove(e)
+	return true
+}
+
+// CompareAndSwap swaps the old and new values for key if the value stored in
+// the LocalMap is equal to old, the key keeps its position. The old value must
+// be of a comparable type.
+func (l *LocalMap[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	e, ok := l.dirty[key]
+	if !ok || any(e.value) != any(old) {
+		return false
+	}
+
+	e.value = new
+	return true
+}
+
+// Compute calls f with the current value for a key, or the zero value if the
+// key is not present, and then performs the returned Op. OpStore sets the
+// value returned by f, a key which was not present is added to the end, and an
+// existing key keeps its position. OpDelete deletes the key and OpKeep leaves
+// the LocalMap unchanged. Compute returns the value for the key afterwards, the
+// present result reports whether the key is in the LocalMap. f must not modify
+// l.
+func (l *LocalMap[K, V]) Compute(key K, f func(old V, loaded bool) (V, Op)) (actual V, present bool) {
+	e, loaded := l.dirty[key]
+	var old V
+	if loaded {
+		old = e.value
+	}
+
+	value, op := f(old, loaded)
+	switch {
+	case op == OpStore && loaded:
+		e.value = value
+	case op == OpStore:
+		l.insert(l.len(), &entry[K, V]{key: key, value: value})
+	case op == OpDelete && loaded:
+		l.remove(e)
+		return actual, false
+	default:
+		return old, loaded
+	}
+	return value, true
+}
+
+// ComputeIfAbsent is Compute, but f is only called if the key is not present.
+func (l *LocalMap[K, V]) ComputeIfAbsent(key K, f func() (V, Op)) (actual V, present bool) {
+	return l.Compute(key, ifAbsent(f))
+}
+
+// ComputeIfPresent is Compute, but f is only called if the key is present.
+func (l *LocalMap[K, V]) ComputeIfPresent(key K, f func(old V) (V, Op)) (actual V, present bool) {
+	return l.Compute(key, ifPresent(f))
+}
+
+// ifAbsent adapts f to a Compute function which keeps present keys.
+func ifAbsent[V any](f func() (V, Op)) func(V, bool) (V, Op) {
+	return func(old V, loaded bool) (V, Op) {
+		if loaded {
+			return old, OpKeep
+		}
+		return f()
+	}
+}
+
+// ifPresent adapts f to a Compute function which keeps absent keys absent.
+func ifPresent[V any](f func(V) (V, Op)) func(V, bool) (V, Op) {
+	return func(old V, loaded bool) (V, Op) {
+		if !loaded {
+			return old, OpKeep
+		}
+		return f(old)
+	}
+}
+
+// Delete deletes the value for a key.
+func (l *LocalMap[K, V]) Delete(key K) {
+	l.LoadAndDelete(key)
+}
+
+// DeleteAll deletes the values for keys, returning the number of keys which
+// were present.
+func (l *LocalMap[K, V]) DeleteAll(keys ...K) (deleted int) {
+	for _, key := range keys {
+		if _, loaded := l.LoadAndDelete(key); loaded {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// DeleteAt deletes the key at index n, returning the key and its value. The
+// deleted result reports whether the index was in range. Negative values of n
+// index from the end of the LocalMap.
+func (l *LocalMap[K, V]) DeleteAt(n int) (key K, value V, deleted bool) {
+	return l.LoadAndDeleteAt(n)
+}
+
+// DeleteRange deletes the keys from index i up to but not including index j,
+// returning the number of keys deleted. Negative values of i and j index from
+// the end of the LocalMap, out of range values are moved to the nearest end.
+func (l *LocalMap[K, V]) DeleteRange(i, j int) (deleted int) {
+	i, j = span(i, j, l.len())
+	if i == j {
+		return 0
+	}
+
+	e := l.order.at(i)
+	for n := i; n < j; n++ {
+		delete(l.dirty, e.key)
+		e = l.order.next(e)
+	}
+	l.order.removeRange(i, j)
+	l.gen++
+	return j - i
+}
+
+// span resolves i and j as the bounds of a range in a map of length ln,
+// negative values index from the end and out of range values are moved to the
+// nearest end. If j is before i the range is empty.
+func span(i, j, ln int) (int, int) {
+	if i < 0 {
+		i += ln
+	}
+	if j < 0 {
+		j += ln
+	}
+	i = max(0, min(i, ln))
+	j = max(i, min(j, ln))
+	return i, j
+}
+
+// Grow allocates room for at least n more keys, so that the next n keys can be
+// added without allocating. Grow does nothing if there is already room,
+// otherwise it is O(len+n) and at least doubles the room, so that calling Grow
+// before each Store is amortised O(1).
+func (l *LocalMap[K, V]) Grow(n int) {
+	if n <= 0 {
+		return
+	}
+
+	l.lazyInit()
+	l.order.grow(n)
+	if len(l.dirty)+n <= l.room {
+		return
+	}
+	l.room = max(len(l.dirty)+n, 2*l.room)
+	dirty := make(map[K]*entry[K, V], l.room)
+	for key, e := range l.dirty {
+		dirty[key] = e
+	}
+	l.dirty = dirty
+}
+
+// Index loads the key and value of the key at index n. The loaded result
+// reports whether the index was in range. Negative value of n index from the
+// end of the LocalMap. Index is O(log n) with the default Tree backing, O(1)
+// with the Deque backing and O(min(n, len-n)) with the List backing.
+func (l *LocalMap[K, V]) Index(n int) (key K, value V, loaded bool) {
+	e := l.index(n)
+	if e == nil {
+		return
+	}
+	return e.key, e.value, true
+}
+
+// IndexOf returns the index of a key. The ok result reports whether the key was
+// in the LocalMap. IndexOf is O(log n) with the default Tree backing, O(1) with
+// the Deque backing and O(i) with the List backing, where i is the index.
+func (l *LocalMap[K, V]) IndexOf(key K) (n int, ok bool) {
+	e, ok := l.dirty[key]
+	if !ok {
+		return 0, false
+	}
+	return l.order.index(e), true
+}
+
+// index returns the entry at index n, or nil if n is out of range.
+func (l *LocalMap[K, V]) index(n int) *entry[K, V] {
+	ln := l.len()
+	if n < 0 {
+		n += ln
+	}
+	if n < 0 || n >= ln {
+		return nil
+	}
+
+	return l.order.at(n)
+}
+
+// InsertAt sets the value for a key and moves it so that it is at index n,
+// adding it if it was not in the map. Negative values of n index from the end
+// of the LocalMap as it will be after the insertion, so -1 is the end. If n is
+// out of range the key is placed at the nearest end. The added result reports
+// whether the key was not already in the map.
+func (l *LocalMap[K, V]) InsertAt(n int, key K, value V) (added bool) {
+	e, ok := l.dirty[key]
+	if ok {
+		l.remove(e)
+	} else {
+		e = &entry[K, V]{key: key}
+	}
+	e.value = value
+
+	l.insert(clamp(n, l.len()+1), e)
+	return !ok
+}
+
+// clamp resolves n as an index into a map of length ln, negative values index
+// from the end and out of range values are moved to the nearest end.
+func clamp(n, ln int) int {
+	if n < 0 {
+		n += ln
+	}
+	return max(0, min(n, ln-1))
+}
+
+// KeepFirst deletes all but the first n keys, returning the deleted keys and
+// their values in order.
+func (l *LocalMap[K, V]) KeepFirst(n int) (keys []K, values []V) {
+	ln := l.len()
+	n = max(0, min(n, ln))
+
+	keys = make([]K, ln-n)
+	values = make([]V, ln-n)
+	for i := ln - 1; i >= n; i-- {
+		e := l.order.at(i)
+		l.remove(e)
+		keys[i-n], values[i-n] = e.key, e.value
+	}
+	return
+}
+
+// KeepLast deletes all but the last n keys, returning the deleted keys and
+// their values in order.
+func (l *LocalMap[K, V]) KeepLast(n int) (keys []K, values []V) {
+	ln := l.len()
+	n = max(0, min(n, ln))
+
+	keys = make([]K, ln-n)
+	values = make([]V, ln-n)
+	for i := range keys {
+		e := l.order.at(0)
+		l.remove(e)
+		keys[i], values[i] = e.key, e.value
+	}
+	return
+}
+
+// Len returns the number of keys in the LocalMap.
+func (l *LocalMap[K, V]) Len() int {
+	return l.len()
+}
+
+func (l *LocalMap[K, V]) len() int {
+	if l.order == nil {
+		return 0
+	}
+	return l.order.len()
+}
+
+// Load returns the value stored in the map for a key, or nil if no value is
+// present. The ok result indicates whether value was found in the map.
+func (l *LocalMap[K, V]) Load(key K) (value V, ok bool) {
+	e, ok := l.dirty[key]
+	if !ok {
+		return
+	}
+	return e.value, true
+}
+
+// LoadAll returns the values stored in the map for keys, in the same order as
+// keys. Each value in loaded reports whether the key at the same index was
+// found in the map.
+func (l *LocalMap[K, V]) LoadAll(keys ...K) (values []V, loaded []bool) {
+	values = make([]V, len(keys))
+	loaded = make([]bool, len(keys))
+	for i, key := range keys {
+		values[i], loaded[i] = l.Load(key)
+	}
+	return values, loaded
+}
+
+// LoadAndDelete deletes the value for a key, returning the previous value if
+// any. The loaded result reports whether the key was present.
+func (l *LocalMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	e, loaded := l.dirty[key]
+	if !loaded {
+		return
+	}
+
+	l.remove(e)
+	return e.value, true
+}
+
+// LoadAndDeleteAt deletes the key at index n, returning the key and its
+// previous value if any. The loaded result reports whether the index was in
+// range. Negative values of n index from the end of the LocalMap.
+func (l *LocalMap[K, V]) LoadAndDeleteAt(n int) (key K, value V, loaded bool) {
+	e := l.index(n)
+	if e == nil {
+		return
+	}
+
+	l.remove(e)
+	return e.key, e.value, true
+}
+
+// LoadAndDeleteFirst deletes the first key, returning the key and its previous
+// value if any. The loaded result reports whether the key was present.
+func (l *LocalMap[K, V]) LoadAndDeleteFirst() (key K, value V, loaded bool) {
+	return l.LoadAndDeleteAt(0)
+}
+
+// LoadAndDeleteLast deletes the last key, returning the key and its previous
+// value if any. The loaded result reports whether the key was present.
+func (l *LocalMap[K, V]) LoadAndDeleteLast() (key K, value V, loaded bool) {
+	return l.LoadAndDeleteAt(-1)
+}
+
+// LoadAndStore sets the value for a key adding it to the end if it was not in
+// the map, returning the previous value if any. The loaded result reports
+// whether the key was present, if it was it keeps its position.
+func (l *LocalMap[K, V]) LoadAndStore(key K, value V) (previous V, loaded bool) {
+	return l.storeAt(l.len(), key, value)
+}
+
+// LoadAndStoreAt sets the value for a key adding it at index n if it was not in
+// the map, returning the previous value if any. The loaded result reports
+// whether the key was present, if it was it keeps its position. n is resolved
+// as in InsertAt.
+func (l *LocalMap[K, V]) LoadAndStoreAt(n int, key K, value V) (previous V, loaded bool) {
+	return l.storeAt(n, key, value)
+}
+
+// LoadAndStoreFirst sets the value for a key adding it to the beginning if it
+// was not in the map, returning the previous value if any. The loaded result
+// reports whether the key was present, if it was it keeps its position.
+func (l *LocalMap[K, V]) LoadAndStoreFirst(key K, value V) (previous V, loaded bool) {
+	return l.storeAt(0, key, value)
+}
+
+// LoadOrCompute returns the existing value for the key if present. Otherwise,
+// it calls f and, if f returns no error, stores and returns its value, adding
+// it to the end. The loaded result is true if the value was loaded, false if
+// it was stored. Errors from f are returned and nothing is stored.
+func (l *LocalMap[K, V]) LoadOrCompute(key K, f func() (V, error)) (actual V, loaded bool, err error) {
+	if actual, loaded = l.Load(key); loaded {
+		return actual, true, nil
+	}
+
+	value, err := f()
+	if err != nil {
+		return actual, false, err
+	}
+	actual, loaded = l.LoadOrStore(key, value)
+	return actual, loaded, nil
+}
+
+// LoadOrStore returns the existing value for the key if present. Otherwise, it
+// stores and returns the given value, adding it to the end. The loaded result
+// is true if the value was loaded, false if stored.
+func (l *LocalMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	actual, loaded = l.Load(key)
+	if !loaded {
+		l.Store(key, value)
+		actual = value
+	}
+	return
+}
+
+// MoveAfter moves a key to immediately after the anchor key. The moved result
+// reports whether both key and anchor were in the LocalMap, if either was not
+// the LocalMap is unchanged.
+func (l *LocalMap[K, V]) MoveAfter(key, anchor K) (moved bool) {
+	return l.moveBeside(key, anchor, 1)
+}
+
+// moveBeside moves a key to offset places after the index of the anchor key,
+// once key has been taken out of the order.
+func (l *LocalMap[K, V]) moveBeside(key, anchor K, offset int) bool {
+	e, ok := l.dirty[key]
+	if !ok {
+		return false
+	}
+	a, ok := l.dirty[anchor]
+	if !ok {
+		return false
+	}
+	if e == a {
+		return true
+	}
+
+	l.remove(e)
+	l.insert(l.order.index(a)+offset, e)
+	return true
+}
+
+// MoveBefore moves a key to immediately before the anchor key. The moved result
+// reports whether both key and anchor were in the LocalMap, if either was not
+// the LocalMap is unchanged.
+func (l *LocalMap[K, V]) MoveBefore(key, anchor K) (moved bool) {
+	return l.moveBeside(key, anchor, 0)
+}
+
+// MoveTo moves a key so that it is at index n. Negative values of n index from
+// the end of the LocalMap, so -1 is the end. If n is out of range the key is
+// moved to the nearest end. The moved result reports whether the key was in the
+// LocalMap.
+func (l *LocalMap[K, V]) MoveTo(key K, n int) (moved bool) {
+	e, ok := l.dirty[key]
+	if !ok {
+		return false
+	}
+
+	l.remove(e)
+	l.insert(clamp(n, l.len()+1), e)
+	return true
+}
+
+// MoveToBack moves a key to the end of the LocalMap. The moved result reports
+// whether the key was in the LocalMap.
+func (l *LocalMap[K, V]) MoveToBack(key K) (moved bool) {
+	return l.MoveTo(key, -1)
+}
+
+// MoveToFront moves a key to the beginning of the LocalMap. The moved result
+// reports whether the key was in the LocalMap.
+func (l *LocalMap[K, V]) MoveToFront(key K) (moved bool) {
+	return l.MoveTo(key, 0)
+}
+
+// Next loads the key after a key and its value. The ok result reports whether
+// the key was in the LocalMap and was not the last key.
+func (l *LocalMap[K, V]) Next(key K) (next K, value V, ok bool) {
+	return l.neighbour(key, true)
+}
+
+// neighbour loads the key after a key and its value, or the key before it if
+// after is false.
+func (l *LocalMap[K, V]) neighbour(key K, after bool) (neighbour K, value V, ok bool) {
+	e, ok := l.dirty[key]
+	if !ok {
+		return
+	}
+	if after {
+		e = l.order.next(e)
+	} else {
+		e = l.order.prev(e)
+	}
+	if e == nil {
+		return neighbour, value, false
+	}
+	return e.key, e.value, true
+}
+
+// PeekFirst loads the first key and its value without deleting it. The loaded
+// result reports whether the LocalMap was non-empty.
+func (l *LocalMap[K, V]) PeekFirst() (key K, value V, loaded bool) {
+	return l.Index(0)
+}
+
+// PeekLast loads the last key and its value without deleting it. The loaded
+// result reports whether the LocalMap was non-empty.
+func (l *LocalMap[K, V]) PeekLast() (key K, value V, loaded bool) {
+	return l.Index(-1)
+}
+
+// PopN deletes up to n keys from the beginning of the LocalMap, or up to -n
+// keys from the end if n is negative, returning the keys and their values in
+// the order they were deleted.
+func (l *LocalMap[K, V]) PopN(n int) (keys []K, values []V) {
+	at := 0
+	if n < 0 {
+		n = -n
+		at = -1
+	}
+	if ln := l.len(); n > ln {
+		n = ln
+	}
+
+	keys = make([]K, 0, n)
+	values = make([]V, 0, n)
+	for ; n > 0; n-- {
+		e := l.index(at)
+		l.remove(e)
+		keys = append(keys, e.key)
+		values = append(values, e.value)
+	}
+	return
+}
+
+// Prev loads the key before a key and its value. The ok result reports whether
+// the key was in the LocalMap and was not the first key.
+func (l *LocalMap[K, V]) Prev(key K) (prev K, value V, ok bool) {
+	return l.neighbour(key, false)
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, range stops the iteration.
+//
+// Every index will be visited in order, if f stores or deletes keys then the
+// keys at later indices shift and Range may skip or repeat them.
+func (l *LocalMap[K, V]) Range(f func(index int, key K, value V) bool) {
+	var (
+		e   *entry[K, V]
+		gen uint64
+	)
+	for index := 0; ; index++ {
+		e, gen = l.seek(e, gen, index)
+		if e == nil {
+			return
+		}
+
+		if !f(index, e.key, e.value) {
+			return
+		}
+	}
+}
+
+// seek returns the entry at index and the current gen given that prev was the
+// entry at index-1 when gen was current.
+func (l *LocalMap[K, V]) seek(prev *entry[K, V], gen uint64, index int) (*entry[K, V], uint64) {
+	if prev != nil && gen == l.gen {
+		return l.order.next(prev), l.gen
+	}
+	// The order changed since prev was found, find the entry at index again.
+	return l.index(index), l.gen
+}
+
+// RangeSnapshot calls f sequentially for each key and value present in the
+// map at the moment RangeSnapshot was called. If f returns false, range stops
+// the iteration. The copy costs O(n) memory.
+func (l *LocalMap[K, V]) RangeSnapshot(f func(index int, key K, value V) bool) {
+	keys, values := l.snapshot()
+	for index := range keys {
+		if !f(index, keys[index], values[index]) {
+			return
+		}
+	}
+}
+
+// snapshot returns copies of the keys and values in order.
+func (l *LocalMap[K, V]) snapshot() (keys []K, values []V) {
+	keys = make([]K, 0, l.len())
+	values = make([]V, 0, l.len())
+	l.each(func(e *entry[K, V]) {
+		keys = append(keys, e.key)
+		values = append(values, e.value)
+	})
+	return
+}
+
+// each calls f for each entry in order.
+func (l *LocalMap[K, V]) each(f func(e *entry[K, V])) {
+	for e := l.index(0); e != nil; e = l.order.next(e) {
+		f(e)
+	}
+}
+
+// Reverse reverses the order of the LocalMap in O(n).
+func (l *LocalMap[K, V]) Reverse() {
+	keys, values := l.snapshot()
+	slices.Reverse(keys)
+	slices.Reverse(values)
+	l.rewrite(keys, values)
+}
+
+// Rotate moves the first n keys to the end of the LocalMap, or the last -n keys
+// to the beginning if n is negative, in O(n). n may be larger than the length
+// of the LocalMap.
+func (l *LocalMap[K, V]) Rotate(n int) {
+	ln := l.len()
+	if ln == 0 {
+		return
+	}
+	if n %= ln; n < 0 {
+		n += ln
+	}
+	if n == 0 {
+		return
+	}
+
+	keys, values := l.snapshot()
+	rotate(keys, n)
+	rotate(values, n)
+	l.rewrite(keys, values)
+}
+
+// rotate moves the first n elements of s to the end in place.
+func rotate[T any](s []T, n int) {
+	slices.Reverse(s[:n])
+	slices.Reverse(s[n:])
+	slices.Reverse(s)
+}
+
+// rewrite replaces the contents of the entries, in order, with keys and values.
+func (l *LocalMap[K, V]) rewrite(keys []K, values []V) {
+	i := 0
+	l.each(func(e *entry[K, V]) {
+		e.key, e.value = keys[i], values[i]
+		l.dirty[e.key] = e
+		i++
+	})
+}
+
+// Slice returns a new LocalMap with the same options holding copies of the
+// keys from index i up to but not including index j, values are copied by
+// assignment. Negative values of i and j index from the end of the LocalMap,
+// out of range values are moved to the nearest end.
+func (l *LocalMap[K, V]) Slice(i, j int) *LocalMap[K, V] {
+	c := &LocalMap[K, V]{}
+	l.slice(c, i, j)
+	return c
+}
+
+// slice copies the keys from index i up to j of l into the empty LocalMap c.
+func (l *LocalMap[K, V]) slice(c *LocalMap[K, V], i, j int) {
+	i, j = span(i, j, l.len())
+	c.opts = l.opts
+	c.lazyInit()
+	c.Grow(j - i)
+	for e := l.index(i); i < j; i, e = i+1, l.order.next(e) {
+		c.insert(c.len(), &entry[K, V]{key: e.key, value: e.value})
+	}
+}
+
+// Store sets the value for a key adding it to the end if it was not in the map.
+func (l *LocalMap[K, V]) Store(key K, value V) {
+	l.storeAt(l.len(), key, value)
+}
+
+// StoreAfter sets the value for a key and moves it to immediately after the
+// anchor key, adding it if it was not in the map. If key and anchor are the
+// same only the value is set. The found result reports whether the anchor was
+// in the map, if it was not the map is unchanged.
+func (l *LocalMap[K, V]) StoreAfter(anchor, key K, value V) (found bool) {
+	return l.storeBeside(anchor, 1, key, value)
+}
+
+// StoreAll sets the values for the keys and values in seq, adding keys which
+// were not in the map to the end in the order they occur in seq. seq is read
+// fully before the LocalMap is changed, a nil seq stores nothing.
+func (l *LocalMap[K, V]) StoreAll(seq iter.Seq2[K, V]) {
+	keys, values := collect(seq)
+	l.storeAll(keys, values, false)
+}
+
+// StoreAllFirst sets the values for the keys and values in seq, adding keys
+// which were not in the map to the beginning as a block in the order they
+// occur in seq. seq is read fully before the LocalMap is changed, a nil seq
+// stores nothing.
+func (l *LocalMap[K, V]) StoreAllFirst(seq iter.Seq2[K, V]) {
+	keys, values := collect(seq)
+	l.storeAll(keys, values, true)
+}
+
+// storeAll sets the values for keys, adding new keys to the end, or to the
+// beginning if first is true. To prepend, the new keys are added to the end and
+// then moved to index 0 starting with the last, so that every move is cheap
+// for every backing.
+func (l *LocalMap[K, V]) storeAll(keys []K, values []V, first bool) {
+	var added []*entry[K, V]
+	for i, key := range keys {
+		if _, loaded := l.storeAt(l.len(), key, values[i]); !loaded && first {
+			added = append(added, l.dirty[key])
+		}
+	}
+	for i := len(added) - 1; i >= 0; i-- {
+		l.remove(added[i])
+		l.insert(0, added[i])
+	}
+}
+
+// collect reads the keys and values from seq, a nil seq is empty.
+func collect[K comparable, V any](seq iter.Seq2[K, V]) (keys []K, values []V) {
+	if seq == nil {
+		return nil, nil
+	}
+	for key, value := range seq {
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values
+}
+
+// StoreBefore sets the value for a key and moves it to immediately before the
+// anchor key, adding it if it was not in the map. If key and anchor are the
+// same only the value is set. The found result reports whether the anchor was
+// in the map, if it was not the map is unchanged.
+func (l *LocalMap[K, V]) StoreBefore(anchor, key K, value V) (found bool) {
+	return l.storeBeside(anchor, 0, key, value)
+}
+
+// storeBeside sets the value for a key and moves it to offset places after
+// the index of the anchor key, once key has been taken out of the order.
+func (l *LocalMap[K, V]) storeBeside(anchor K, offset int, key K, value V) bool {
+	a, ok := l.dirty[anchor]
+	if !ok {
+		return false
+	}
+	if key == anchor {
+		a.value = value
+		return true
+	}
+
+	e, ok := l.dirty[key]
+	if ok {
+		l.remove(e)
+	} else {
+		e = &entry[K, V]{key: key}
+	}
+	e.value = value
+
+	l.insert(l.order.index(a)+offset, e)
+	return true
+}
+
+// StoreFirst sets the value for a key adding it to the beginning if it was not
+// in the map.
+func (l *LocalMap[K, V]) StoreFirst(key K, value V) {
+	l.storeAt(0, key, value)
+}
+
+// storeAt sets the value for a key adding it at index n if it was not in the
+// map, returning the previous value if any. n is resolved as in InsertAt.
+func (l *LocalMap[K, V]) storeAt(n int, key K, value V) (previous V, loaded bool) {
+	if e, ok := l.dirty[key]; ok {
+		previous, e.value = e.value, value
+		return previous, true
+	}
+
+	l.insert(clamp(n, l.len()+1), &entry[K, V]{key: key, value: value})
+	return
+}
+
+// lazyInit initialises the zero LocalMap.
+func (l *LocalMap[K, V]) lazyInit() {
+	if l.order == nil {
+		l.order = newBacking[K, V](l.opts.backing)
+		l.order.grow(l.opts.capacity)
+	}
+	if l.dirty == nil {
+		l.room = l.opts.capacity
+		l.dirty = make(map[K]*entry[K, V], l.room)
+	}
+}
+
+// insert inserts e into the order at index n and indexes it by key.
+func (l *LocalMap[K, V]) insert(n int, e *entry[K, V]) {
+	l.lazyInit()
+	l.order.insert(n, e)
+	l.gen++
+	l.dirty[e.key] = e
+}
+
+// remove removes e from the order and deletes it from the index.
+func (l *LocalMap[K, V]) remove(e *entry[K, V]) {
+	l.order.remove(e)
+	l.gen++
+	delete(l.dirty, e.key)
+}
+
+// String formats the map for printing
+func (l *LocalMap[K, V]) String() string {
+	return typeName(l) + l.string()
+}
+
+func (l *LocalMap[K, V]) string() (s string) {
+	s = "["
+	var space string
+	l.each(func(e *entry[K, V]) {
+		s += space + fmt.Sprint(e.key) + ":" + fmt.Sprint(e.value)
+		space = " "
+	})
+	s += "]"
+	return
+}
+
+// Swap swaps the position of the keys at indicies i and j.
+func (l *LocalMap[K, V]) Swap(i, j int) {
+	if i < 0 || i >= l.len() || j < 0 || j >= l.len() {
+		return
+	}
+
+	l.swap(l.index(i), l.index(j))
+}
+
+// SwapAt swaps the positions of the keys at indices i and j. Negative values of
+// i and j index from the end of the LocalMap. The swapped result reports
+// whether both indices were in range, if either was not the LocalMap is
+// unchanged.
+func (l *LocalMap[K, V]) SwapAt(i, j int) (swapped bool) {
+	a, b := l.index(i), l.index(j)
+	if a == nil || b == nil {
+		return false
+	}
+
+	l.swap(a, b)
+	return true
+}
+
+// SwapKeys swaps the positions of keys a and b. The swapped result reports
+// whether both keys were in the LocalMap, if either was not the LocalMap is
+// unchanged.
+func (l *LocalMap[K, V]) SwapKeys(a, b K) (swapped bool) {
+	ea, ok := l.dirty[a]
+	if !ok {
+		return false
+	}
+	eb, ok := l.dirty[b]
+	if !ok {
+		return false
+	}
+
+	l.swap(ea, eb)
+	return true
+}
+
+// swap swaps the positions of entries a and b by exchanging their contents.
+func (l *LocalMap[K, V]) swap(a, b *entry[K, V]) {
+	a.key, b.key = b.key, a.key
+	a.value, b.value = b.value, a.value
+	l.dirty[a.key] = a
+	l.dirty[b.key] = b
+}
+
+// ToMap moves the contents of l into a new Map in O(1). l is left empty and
+// ready for use.
+func (l *LocalMap[K, V]) ToMap() *Map[K, V] {
+	m := &Map[K, V]{}
+	m.local.move(l)
+	return m
+}
+
+// move moves the contents of from into l, leaving from empty. The gen of from
+// keeps counting up so that a Range over from does not follow its old entries.
+func (l *LocalMap[K, V]) move(from *LocalMap[K, V]) {
+	*l = *from
+	*from = LocalMap[K, V]{opts: l.opts, gen: from.gen + 1}
+}
+
+// View returns a read-only View of the keys from index i up to but not
+// including index j, without copying them. Negative values of i and j index
+// from the end of the LocalMap, out of range values are moved to the nearest
+// end.
+func (l *LocalMap[K, V]) View(i, j int) *View[K, V] {
+	return &View[K, V]{local: l, i: i, j: j}
+}
+
+// SortLocalMap is a LocalMap which fully impliments sort.Interface. It is not
+// sortable if the key type is float and NaN is used as a key.
+type SortLocalMap[K Ordered, V any] struct {
+	LocalMap[K, V]
+}
+
+// NewSortLocalMap returns an empty SortLocalMap configured by opts.
+func NewSortLocalMap[K Ordered, V any](opts ...Option) *SortLocalMap[K, V] {
+	l := &SortLocalMap[K, V]{}
+	l.init(opts)
+	return l
+}
+
+// Clone returns an independent copy of l with the same order and options.
+// Values which implement Cloner are copied with their Clone method, other
+// values are copied by assignment.
+func (l *SortLocalMap[K, V]) Clone() *SortLocalMap[K, V] {
+	return l.CloneFunc(cloneValue[V])
+}
+
+// CloneFunc returns an independent copy of l with the same order and options,
+// each value is copied by calling f.
+func (l *SortLocalMap[K, V]) CloneFunc(f func(V) V) *SortLocalMap[K, V] {
+	c := &SortLocalMap[K, V]{}
+	l.clone(&c.LocalMap, f)
+	return c
+}
+
+// Slice returns a new SortLocalMap with the same options holding copies of the
+// keys from index i up to but not including index j, values are copied by
+// assignment. Negative values of i and j index from the end of the
+// SortLocalMap, out of range values are moved to the nearest end.
+func (l *SortLocalMap[K, V]) Slice(i, j int) *SortLocalMap[K, V] {
+	c := &SortLocalMap[K, V]{}
+	l.slice(&c.LocalMap, i, j)
+	return c
+}
+
+// Less returns true if the key at index i is less than the key at index j.
+func (l *SortLocalMap[K, V]) Less(i, j int) bool {
+	return less(&l.LocalMap, i, j)
+}
+
+func less[K Ordered, V any](l *LocalMap[K, V], i, j int) bool {
+	if i < 0 || i >= l.len() || j < 0 || j >= l.len() {
+		return false
+	}
+
+	return l.index(i).key < l.index(j).key
+}
+
+// String formats the map for printing
+func (l *SortLocalMap[K, V]) String() string {
+	return typeName(l) + l.string()
+}
@@ -0,0 +1,196 @@
+package ordered
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// TestLocalMapMethods checks that LocalMap has the same methods as Map.
+func TestLocalMapMethods(t *testing.T) {
+	for name, test := range map[string]struct {
+		a, b reflect.Type
+		skip map[string]bool
+	}{
+		"Map": {
+			a:    reflect.TypeOf(&Map[int, int]{}),
+			b:    reflect.TypeOf(&LocalMap[int, int]{}),
+			skip: map[string]bool{"ToLocal": true, "ToMap": true},
+		},
+		"SortMap": {
+			a:    reflect.TypeOf(&SortMap[int, int]{}),
+			b:    reflect.TypeOf(&SortLocalMap[int, int]{}),
+			skip: map[string]bool{"ToLocal": true, "ToMap": true},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, types := range [][2]reflect.Type{{test.a, test.b}, {test.b, test.a}} {
+				for i := 0; i < types[0].NumMethod(); i++ {
+					method := types[0].Method(i)
+					if test.skip[method.Name] {
+						continue
+					}
+					other, ok := types[1].MethodByName(method.Name)
+					if !ok {
+						t.Errorf("%s has method %s but %s does not", types[0], method.Name, types[1])
+						continue
+					}
+					if method.Type.NumIn() != other.Type.NumIn() || method.Type.NumOut() != other.Type.NumOut() {
+						t.Errorf("%s.%s and %s.%s have different signatures", types[0], method.Name, types[1], method.Name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestToMap(t *testing.T) {
+	l := NewLocalMap[string, int](WithBacking(List))
+	fill(l, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+
+	m := l.ToMap()
+	checkContent(t, m, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+	checkLocalContent(t, l, nil, nil)
+	if _, ok := m.local.order.(*list[string, int]); !ok {
+		t.Errorf("Unexpected backing %T", m.local.order)
+	}
+
+	l.Store("three", 3)
+	checkLocalContent(t, l, []string{"three"}, map[string]int{"three": 3})
+	checkContent(t, m, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+}
+
+func TestToLocal(t *testing.T) {
+	m := NewMap[string, int](WithBacking(Tree))
+	fill(&m.local, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+
+	l := m.ToLocal()
+	checkLocalContent(t, l, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+	checkContent(t, m, nil, nil)
+	if _, ok := l.order.(*tree[string, int]); !ok {
+		t.Errorf("Unexpected backing %T", l.order)
+	}
+
+	m.Store("three", 3)
+	checkContent(t, m, []string{"three"}, map[string]int{"three": 3})
+	checkLocalContent(t, l, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+}
+
+// TestToLocalDuringRange checks that Range stops at the moved out entries
+// after the Map is refilled during f.
+func TestToLocalDuringRange(t *testing.T) {
+	m := NewMap[string, int](WithBacking(List))
+	fill(&m.local, []string{"a", "b"}, map[string]int{"a": 1, "b": 2})
+
+	var got []string
+	m.Range(func(index int, key string, value int) bool {
+		got = append(got, key)
+		if index == 0 {
+			m.ToLocal()
+			m.Store("x", 3)
+			m.Store("y", 4)
+		}
+		return index < 10
+	})
+	if want := []string{"a", "y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected keys, wanted %#v but got %#v", want, got)
+	}
+}
+
+func TestLocalMapRange(t *testing.T) {
+	l := &LocalMap[string, int]{}
+	fill(l, []string{"one", "two", "three", "four"}, map[string]int{"one": 1, "two": 2, "three": 3, "four": 4})
+
+	var got []string
+	l.Range(func(index int, key string, value int) bool {
+		got = append(got, key)
+		if key == "two" {
+			l.Delete("three")
+			l.Store("five", 5)
+		}
+		return true
+	})
+	if want := []string{"one", "two", "four", "five"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got unexpected keys\nactual: %#v\nwant  : %#v", got, want)
+	}
+
+	got = nil
+	l.RangeSnapshot(func(index int, key string, value int) bool {
+		got = append(got, key)
+		l.Delete(key)
+		return true
+	})
+	if want := []string{"one", "two", "four", "five"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got unexpected keys\nactual: %#v\nwant  : %#v", got, want)
+	}
+	checkLocalContent(t, l, []string{}, map[string]int{})
+}
+
+func TestSortLocalMap(t *testing.T) {
+	l := NewSortLocalMap[float64, string]()
+	for i := 0; i < 1000; i++ {
+		n := rand.Float64()
+		l.Store(n, strconv.FormatFloat(n, 'E', -1, 64))
+	}
+	sort.Sort(l)
+	if !sort.IsSorted(l) {
+		t.Error("It should be sorted")
+	}
+}
+
+func TestLocalMapString(t *testing.T) {
+	for name, test := range map[string]struct {
+		object fmt.Stringer
+		want   string
+	}{
+		"LocalMap": {
+			want: "github.com/brackendawson/ordered.LocalMap[string,int][seven:7 one:1]",
+			object: func() fmt.Stringer {
+				l := &LocalMap[string, int]{}
+				fill(l, []string{"seven", "one"}, map[string]int{"one": 1, "seven": 7})
+				return l
+			}(),
+		},
+		"SortLocalMap": {
+			want: "github.com/brackendawson/ordered.SortLocalMap[float64,bool][6.7:true -0.2:false]",
+			object: func() fmt.Stringer {
+				l := &SortLocalMap[float64, bool]{}
+				fill(&l.LocalMap, []float64{6.7, -0.2}, map[float64]bool{6.7: true})
+				return l
+			}(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := test.object.String()
+			if test.want != got {
+				t.Errorf("Not equal:\n\twant: %s\n\tgot : %s", test.want, got)
+			}
+		})
+	}
+}
+
+func BenchmarkLocal(b *testing.B) {
+	type storeLoader interface {
+		Store(int, int)
+		Load(int) (int, bool)
+	}
+	for name, newMap := range map[string]func() storeLoader{
+		"Map":      func() storeLoader { return &Map[int, int]{} },
+		"LocalMap": func() storeLoader { return &LocalMap[int, int]{} },
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m := newMap()
+				for i := 0; i < 1000; i++ {
+					m.Store(i, i)
+				}
+				for i := 0; i < 1000; i++ {
+					m.Load(i)
+				}
+			}
+		})
+	}
+}
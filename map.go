@@ -2,7 +2,7 @@
 package ordered
 
 import (
-	"fmt"
+	"iter"
 	"reflect"
 	"sync"
 )
@@ -10,12 +10,165 @@ import (
 // Map is an ordered map data structure that is safe for concurrent use by
 // multiple goroutines without additional locking or coordination.
 //
-// The zero Map is empty and ready for use. A Map must not be copied after first
-// use.
+// The zero Map is empty and ready for use, it uses the Tree backing. A Map
+// must not be copied after first use.
 type Map[K comparable, V any] struct {
-	order []K
-	dirty map[K]V
+	local LocalMap[K, V]
 	mu    sync.RWMutex
+
+	// calls holds the in-flight LoadOrCompute constructors by key.
+	calls map[K]*call[V]
+}
+
+// Option configures a map created by NewMap, NewSortMap, NewLocalMap or
+// NewSortLocalMap.
+type Option func(*options)
+
+type options struct {
+	backing  Backing
+	capacity int
+}
+
+// WithBacking selects the data structure that holds the order of the Map.
+func WithBacking(b Backing) Option {
+	return func(o *options) {
+		o.backing = b
+	}
+}
+
+// WithCapacity allocates room for n keys when the Map is created.
+func WithCapacity(n int) Option {
+	return func(o *options) {
+		o.capacity = n
+	}
+}
+
+// Pair is a key and its value.
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Pairs returns an iterator over the keys and values in pairs, in order, for
+// use with StoreAll and StoreAllFirst.
+func Pairs[K comparable, V any](pairs []Pair[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, p := range pairs {
+			if !yield(p.Key, p.Value) {
+				return
+			}
+		}
+	}
+}
+
+// NewMap returns an empty Map configured by opts.
+func NewMap[K comparable, V any](opts ...Option) *Map[K, V] {
+	m := &Map[K, V]{}
+	m.local.init(opts)
+	return m
+}
+
+// Clear deletes all of the keys, the Map keeps its options.
+func (m *Map[K, V]) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.Clear()
+}
+
+// Clone returns an independent copy of m with the same order and options, it
+// is copied atomically under a single read lock. Values which implement Cloner
+// are copied with their Clone method, other values are copied by assignment.
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	return m.CloneFunc(cloneValue[V])
+}
+
+// CloneFunc returns an independent copy of m with the same order and options,
+// each value is copied by calling f. It is copied atomically under a single
+// read lock, f must not call any method on m.
+func (m *Map[K, V]) CloneFunc(f func(V) V) *Map[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c := &Map[K, V]{}
+	m.local.clone(&c.local, f)
+	return c
+}
+
+// Compact releases the memory held for deleted keys. The order is shrunk to
+// fit the current keys and the index of keys is rebuilt, Compact is O(n).
+func (m *Map[K, V]) Compact() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.Compact()
+}
+
+// CompareAndDelete deletes the key if its value is equal to old. The old value
+// must be of a comparable type.
+//
+// If there is no current value for key in the Map, CompareAndDelete returns
+// false (even if the old value is the nil interface value).
+func (m *Map[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.CompareAndDelete(key, old)
+}
+
+// CompareAndSwap swaps the old and new values for key if the value stored in
+// the Map is equal to old, the key keeps its position. The old value must be
+// of a comparable type.
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.CompareAndSwap(key, old, new)
+}
+
+// Op is the operation performed by Compute and its variants once their
+// function returns.
+type Op int
+
+const (
+	// OpKeep leaves the map unchanged.
+	OpKeep Op = iota
+	// OpStore sets the value returned by the function.
+	OpStore
+	// OpDelete deletes the key.
+	OpDelete
+)
+
+// Compute calls f with the current value for a key, or the zero value if the
+// key is not present, and then performs the returned Op. OpStore sets the
+// value returned by f, a key which was not present is added to the end, and an
+// existing key keeps its position. OpDelete deletes the key and OpKeep leaves
+// the Map unchanged. Compute returns the value for the key afterwards, the
+// present result reports whether the key is in the Map.
+//
+// Compute holds the Map's lock while f runs, so it is atomic with respect to
+// all other methods. f must not call any method on m.
+func (m *Map[K, V]) Compute(key K, f func(old V, loaded bool) (V, Op)) (actual V, present bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.Compute(key, f)
+}
+
+// ComputeIfAbsent is Compute, but f is only called if the key is not present.
+func (m *Map[K, V]) ComputeIfAbsent(key K, f func() (V, Op)) (actual V, present bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.ComputeIfAbsent(key, f)
+}
+
+// ComputeIfPresent is Compute, but f is only called if the key is present.
+func (m *Map[K, V]) ComputeIfPresent(key K, f func(old V) (V, Op)) (actual V, present bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.ComputeIfPresent(key, f)
 }
 
 // Delete deletes the vlaue for a key
@@ -23,27 +176,93 @@ func (m *Map[K, V]) Delete(key K) {
 	m.LoadAndDelete(key)
 }
 
+// DeleteAll deletes the values for keys under a single lock, returning the
+// number of keys which were present.
+func (m *Map[K, V]) DeleteAll(keys ...K) (deleted int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.DeleteAll(keys...)
+}
+
+// DeleteAt deletes the key at index n, returning the key and its value. The
+// deleted result reports whether the index was in range. Negative values of n
+// index from the end of the Map.
+func (m *Map[K, V]) DeleteAt(n int) (key K, value V, deleted bool) {
+	return m.LoadAndDeleteAt(n)
+}
+
+// DeleteRange deletes the keys from index i up to but not including index j,
+// returning the number of keys deleted, under a single lock. Negative values
+// of i and j index from the end of the Map, out of range values are moved to
+// the nearest end.
+func (m *Map[K, V]) DeleteRange(i, j int) (deleted int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.DeleteRange(i, j)
+}
+
+// Grow allocates room for at least n more keys, so that the next n keys can be
+// added without allocating. Grow does nothing if there is already room,
+// otherwise it is O(len+n) and at least doubles the room, so that calling Grow
+// before each Store is amortised O(1).
+func (m *Map[K, V]) Grow(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.Grow(n)
+}
+
 // Index loads the key and value of the key at index n. The loaded result
 // reports whether the index was in range. Negative value of n index from the
-// end of the Map.
+// end of the Map. Index is O(log n) with the default Tree backing, O(1) with
+// the Deque backing and O(min(n, len-n)) with the List backing.
 func (m *Map[K, V]) Index(n int) (key K, value V, loaded bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if n >= 0 {
-		if len(m.order) <= n {
-			return
-		}
-		key = m.order[n]
-	} else {
-		if n < -len(m.order) {
-			return
-		}
-		key = m.order[len(m.order)+n]
-	}
+	return m.local.Index(n)
+}
+
+// IndexOf returns the index of a key. The ok result reports whether the key
+// was in the Map. IndexOf is O(log n) with the default Tree backing, O(1) with
+// the Deque backing and O(i) with the List backing, where i is the index.
+func (m *Map[K, V]) IndexOf(key K) (n int, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.local.IndexOf(key)
+}
+
+// InsertAt sets the value for a key and moves it so that it is at index n,
+// adding it if it was not in the map. Negative values of n index from the end
+// of the Map as it will be after the insertion, so -1 is the end. If n is out
+// of range the key is placed at the nearest end. The added result reports
+// whether the key was not already in the map.
+func (m *Map[K, V]) InsertAt(n int, key K, value V) (added bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.InsertAt(n, key, value)
+}
+
+// KeepFirst deletes all but the first n keys under a single lock, returning
+// the deleted keys and their values in order.
+func (m *Map[K, V]) KeepFirst(n int) (keys []K, values []V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.KeepFirst(n)
+}
+
+// KeepLast deletes all but the last n keys under a single lock, returning the
+// deleted keys and their values in order.
+func (m *Map[K, V]) KeepLast(n int) (keys []K, values []V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	value, loaded = m.dirty[key]
-	return
+	return m.local.KeepLast(n)
 }
 
 // Len returns the number of keys in Map
@@ -51,7 +270,7 @@ func (m *Map[K, V]) Len() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return len(m.order)
+	return m.local.len()
 }
 
 // Load returns the value stored in the map for a key, or nil if no value is
@@ -60,12 +279,17 @@ func (m *Map[K, V]) Load(key K) (value V, ok bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.load(key)
+	return m.local.Load(key)
 }
 
-func (m *Map[K, V]) load(key K) (value V, ok bool) {
-	value, ok = m.dirty[key]
-	return
+// LoadAll returns the values stored in the map for keys, in the same order as
+// keys, under a single read lock. Each value in loaded reports whether the key
+// at the same index was found in the map.
+func (m *Map[K, V]) LoadAll(keys ...K) (values []V, loaded []bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.local.LoadAll(keys...)
 }
 
 // LoadAndDelete deletes the value for a key, returning the previous value if
@@ -74,52 +298,133 @@ func (m *Map[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for i := 0; i < len(m.order); i++ {
-		if m.order[i] != key {
-			continue
-		}
+	return m.local.LoadAndDelete(key)
+}
 
-		m.order = append(m.order[:i], m.order[i+1:]...)
-		break
-	}
+// LoadAndDeleteAt deletes the key at index n, returning the key and its
+// previous value if any. The loaded result reports whether the index was in
+// range. Negative values of n index from the end of the Map.
+func (m *Map[K, V]) LoadAndDeleteAt(n int) (key K, value V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	value, loaded = m.dirty[key]
-	delete(m.dirty, key)
-	return
+	return m.local.LoadAndDeleteAt(n)
 }
 
-// LoadAndDeleteFirst delered the last key, returning the kay and its previous
+// LoadAndDeleteFirst deletes the first key, returning the key and its previous
 // value if any. The loaded result reports whether the key was present.
 func (m *Map[K, V]) LoadAndDeleteFirst() (key K, value V, loaded bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if len(m.order) < 1 {
-		return
-	}
-
-	key = m.order[0]
-
-	value, loaded = m.dirty[key]
-	delete(m.dirty, key)
-	return
+	return m.local.LoadAndDeleteFirst()
 }
 
-// LoadAndDeleteLast delered the last key, returning the kay and its previous
+// LoadAndDeleteLast deletes the last key, returning the key and its previous
 // value if any. The loaded result reports whether the key was present.
 func (m *Map[K, V]) LoadAndDeleteLast() (key K, value V, loaded bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if len(m.order) < 1 {
-		return
+	return m.local.LoadAndDeleteLast()
+}
+
+// LoadAndStore sets the value for a key adding it to the end if it was not in
+// the map, returning the previous value if any. The loaded result reports
+// whether the key was present, if it was it keeps its position.
+func (m *Map[K, V]) LoadAndStore(key K, value V) (previous V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.LoadAndStore(key, value)
+}
+
+// LoadAndStoreAt sets the value for a key adding it at index n if it was not in
+// the map, returning the previous value if any. The loaded result reports
+// whether the key was present, if it was it keeps its position. n is resolved
+// as in InsertAt.
+func (m *Map[K, V]) LoadAndStoreAt(n int, key K, value V) (previous V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.LoadAndStoreAt(n, key, value)
+}
+
+// LoadAndStoreFirst sets the value for a key adding it to the beginning if it
+// was not in the map, returning the previous value if any. The loaded result
+// reports whether the key was present, if it was it keeps its position.
+func (m *Map[K, V]) LoadAndStoreFirst(key K, value V) (previous V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.LoadAndStoreFirst(key, value)
+}
+
+// call is an in-flight LoadOrCompute constructor, done is closed once it has
+// finished. returned is false if the constructor panicked.
+type call[V any] struct {
+	done     chan struct{}
+	value    V
+	err      error
+	returned bool
+}
+
+// LoadOrCompute returns the existing value for the key if present. Otherwise,
+// it calls f and, if f returns no error, stores and returns its value, adding
+// it to the end. The loaded result is true if the value was loaded, false if
+// it was stored by this call. Errors from f are returned and nothing is
+// stored, so the next call for the key calls its f again.
+//
+// The Map is not locked while f runs. Concurrent calls for the same key wait
+// for the f which is already running and share its value or error, rather
+// than calling their own. If the key is stored by another method while f runs
+// the stored value is returned and the value from f is discarded.
+func (m *Map[K, V]) LoadOrCompute(key K, f func() (V, error)) (actual V, loaded bool, err error) {
+	m.mu.Lock()
+	for {
+		if actual, loaded = m.local.Load(key); loaded {
+			m.mu.Unlock()
+			return actual, true, nil
+		}
+		c, ok := m.calls[key]
+		if !ok {
+			break
+		}
+		m.mu.Unlock()
+
+		<-c.done
+		if c.returned {
+			return c.value, c.err == nil, c.err
+		}
+		// The constructor panicked, try again.
+		m.mu.Lock()
 	}
 
-	key = m.order[len(m.order)-1]
+	if m.calls == nil {
+		m.calls = make(map[K]*call[V])
+	}
+	c := &call[V]{done: make(chan struct{})}
+	m.calls[key] = c
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.calls, key)
+		m.mu.Unlock()
+		close(c.done)
+	}()
+
+	value, err := f()
+	if err != nil {
+		c.err, c.returned = err, true
+		return actual, false, err
+	}
 
-	value, loaded = m.dirty[key]
-	delete(m.dirty, key)
-	return
+	m.mu.Lock()
+	actual, loaded = m.local.LoadOrStore(key, value)
+	m.mu.Unlock()
+	c.value, c.returned = actual, true
+	return actual, loaded, nil
 }
 
 // LoadOrStore returns the existing value for the key if present. Otherwise, it
@@ -129,60 +434,234 @@ func (m *Map[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	actual, loaded = m.load(key)
-	if !loaded {
-		m.store(key, value)
-		actual = value
-	}
-	return
+	return m.local.LoadOrStore(key, value)
+}
+
+// MoveAfter moves a key to immediately after the anchor key. The moved result
+// reports whether both key and anchor were in the Map, if either was not the
+// Map is unchanged.
+func (m *Map[K, V]) MoveAfter(key, anchor K) (moved bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.MoveAfter(key, anchor)
+}
+
+// MoveBefore moves a key to immediately before the anchor key. The moved
+// result reports whether both key and anchor were in the Map, if either was not
+// the Map is unchanged.
+func (m *Map[K, V]) MoveBefore(key, anchor K) (moved bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.MoveBefore(key, anchor)
+}
+
+// MoveTo moves a key so that it is at index n. Negative values of n index from
+// the end of the Map, so -1 is the end. If n is out of range the key is moved
+// to the nearest end. The moved result reports whether the key was in the Map.
+func (m *Map[K, V]) MoveTo(key K, n int) (moved bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.MoveTo(key, n)
+}
+
+// MoveToBack moves a key to the end of the Map. The moved result reports
+// whether the key was in the Map.
+func (m *Map[K, V]) MoveToBack(key K) (moved bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.MoveToBack(key)
+}
+
+// MoveToFront moves a key to the beginning of the Map. The moved result reports
+// whether the key was in the Map.
+func (m *Map[K, V]) MoveToFront(key K) (moved bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.MoveToFront(key)
+}
+
+// Next loads the key after a key and its value. The ok result reports whether
+// the key was in the Map and was not the last key.
+func (m *Map[K, V]) Next(key K) (next K, value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.local.Next(key)
+}
+
+// PeekFirst loads the first key and its value without deleting it. The loaded
+// result reports whether the Map was non-empty.
+func (m *Map[K, V]) PeekFirst() (key K, value V, loaded bool) {
+	return m.Index(0)
+}
+
+// PeekLast loads the last key and its value without deleting it. The loaded
+// result reports whether the Map was non-empty.
+func (m *Map[K, V]) PeekLast() (key K, value V, loaded bool) {
+	return m.Index(-1)
+}
+
+// PopN deletes up to n keys from the beginning of the Map, or up to -n keys
+// from the end if n is negative, returning the keys and their values in the
+// order they were deleted.
+func (m *Map[K, V]) PopN(n int) (keys []K, values []V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.PopN(n)
+}
+
+// Prev loads the key before a key and its value. The ok result reports whether
+// the key was in the Map and was not the first key.
+func (m *Map[K, V]) Prev(key K) (prev K, value V, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.local.Prev(key)
 }
 
 // Range calls f sequentially for each key and value present in the map. If f
 // returns false, range stops the iteration.
 //
 // Range does not necessarily correspond to any consistent snapshot of the Map's
-// contents: nevery index will be visited in order, but if the value for any key
-// stored or deleted concurrently (including by f), Range may reflect any
-// mapping for that key from any point during the Range call. Range does not
-// block other methods on the receiver; even f itself may call any method on m.
+// contents: every index will be visited in order, each call to f receives the
+// key and value at that index at the moment it was loaded. If keys are stored
+// or deleted concurrently (including by f), the keys at later indices shift and
+// Range may skip or repeat them. Range does not block other methods on the
+// receiver; even f itself may call any method on m. Use RangeSnapshot to
+// iterate over a consistent snapshot.
 func (m *Map[K, V]) Range(f func(index int, key K, value V) bool) {
-	m.mu.RLock()
-
-	if len(m.order) < 1 {
-		m.mu.RUnlock()
-		return
-	}
-
-	for index, key := range m.order {
-		if index == 0 {
+	var (
+		e   *entry[K, V]
+		gen uint64
+	)
+	for index := 0; ; index++ {
+		m.mu.RLock()
+		e, gen = m.local.seek(e, gen, index)
+		if e == nil {
 			m.mu.RUnlock()
+			return
 		}
+		key, value := e.key, e.value
+		m.mu.RUnlock()
 
-		if f(index, key, m.dirty[key]) {
-			continue
+		if !f(index, key, value) {
+			return
 		}
+	}
+}
 
-		return
+// RangeSnapshot calls f sequentially for each key and value present in the
+// map at the moment RangeSnapshot was called. If f returns false, range stops
+// the iteration.
+//
+// RangeSnapshot copies the Map's contents under a single read lock, so f sees a
+// consistent snapshot regardless of concurrent writes and may call any method
+// on m. The copy costs O(n) memory.
+func (m *Map[K, V]) RangeSnapshot(f func(index int, key K, value V) bool) {
+	m.mu.RLock()
+	keys, values := m.local.snapshot()
+	m.mu.RUnlock()
+
+	for index := range keys {
+		if !f(index, keys[index], values[index]) {
+			return
+		}
 	}
 }
 
+// Reverse reverses the order of the Map in O(n) under a single lock.
+func (m *Map[K, V]) Reverse() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.Reverse()
+}
+
+// Rotate moves the first n keys to the end of the Map, or the last -n keys to
+// the beginning if n is negative, in O(n) under a single lock. n may be larger
+// than the length of the Map.
+func (m *Map[K, V]) Rotate(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.Rotate(n)
+}
+
+// Slice returns a new Map with the same options holding copies of the keys
+// from index i up to but not including index j, it is copied atomically under
+// a single read lock and values are copied by assignment. Negative values of i
+// and j index from the end of the Map, out of range values are moved to the
+// nearest end.
+func (m *Map[K, V]) Slice(i, j int) *Map[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c := &Map[K, V]{}
+	m.local.slice(&c.local, i, j)
+	return c
+}
+
 // Store sets the value for a key adding it to the end if it was not in the map.
 func (m *Map[K, V]) Store(key K, value V) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.store(key, value)
+	m.local.Store(key, value)
 }
 
-func (m *Map[K, V]) store(key K, value V) {
-	if _, ok := m.dirty[key]; !ok {
-		m.order = append(m.order, key)
-	}
+// StoreAfter sets the value for a key and moves it to immediately after the
+// anchor key, adding it if it was not in the map. If key and anchor are the
+// same only the value is set. The found result reports whether the anchor was
+// in the map, if it was not the map is unchanged.
+func (m *Map[K, V]) StoreAfter(anchor, key K, value V) (found bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if m.dirty == nil {
-		m.dirty = make(map[K]V)
-	}
-	m.dirty[key] = value
+	return m.local.StoreAfter(anchor, key, value)
+}
+
+// StoreAll sets the values for the keys and values in seq under a single lock,
+// adding keys which were not in the map to the end in the order they occur in
+// seq. seq is read fully before the Map is locked, so other goroutines see
+// either none or all of it, a nil seq stores nothing. Use Pairs to store a
+// slice of Pair.
+func (m *Map[K, V]) StoreAll(seq iter.Seq2[K, V]) {
+	keys, values := collect(seq)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.storeAll(keys, values, false)
+}
+
+// StoreAllFirst sets the values for the keys and values in seq under a single
+// lock, adding keys which were not in the map to the beginning as a block in
+// the order they occur in seq. seq is read fully before the Map is locked, so
+// other goroutines see either none or all of it, a nil seq stores nothing.
+func (m *Map[K, V]) StoreAllFirst(seq iter.Seq2[K, V]) {
+	keys, values := collect(seq)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.local.storeAll(keys, values, true)
+}
+
+// StoreBefore sets the value for a key and moves it to immediately before the
+// anchor key, adding it if it was not in the map. If key and anchor are the
+// same only the value is set. The found result reports whether the anchor was
+// in the map, if it was not the map is unchanged.
+func (m *Map[K, V]) StoreBefore(anchor, key K, value V) (found bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.StoreBefore(anchor, key, value)
 }
 
 // StoreFirst sets the value for a key adding it to the beginning if it was not
@@ -191,14 +670,7 @@ func (m *Map[K, V]) StoreFirst(key K, value V) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.dirty[key]; !ok {
-		m.order = append([]K{key}, m.order...)
-	}
-
-	if m.dirty == nil {
-		m.dirty = make(map[K]V)
-	}
-	m.dirty[key] = value
+	m.local.StoreFirst(key, value)
 }
 
 // String formats the map for printing
@@ -206,18 +678,11 @@ func (m *Map[K, V]) String() string {
 	return typeName(m) + m.string()
 }
 
-func (m *Map[K, V]) string() (s string) {
+func (m *Map[K, V]) string() string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	s = "["
-	var space string
-	for i := range m.order {
-		s += space + fmt.Sprint(m.order[i]) + ":" + fmt.Sprint(m.dirty[m.order[i]])
-		space = " "
-	}
-	s += "]"
-	return
+	return m.local.string()
 }
 
 // Swap swaps the position of the keys at indicies i and j.
@@ -225,11 +690,44 @@ func (m *Map[K, V]) Swap(i, j int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if i < 0 || i >= len(m.order) || j < 0 || j >= len(m.order) {
-		return
-	}
+	m.local.Swap(i, j)
+}
+
+// SwapAt swaps the positions of the keys at indices i and j. Negative values of
+// i and j index from the end of the Map. The swapped result reports whether
+// both indices were in range, if either was not the Map is unchanged.
+func (m *Map[K, V]) SwapAt(i, j int) (swapped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.SwapAt(i, j)
+}
+
+// SwapKeys swaps the positions of keys a and b. The swapped result reports
+// whether both keys were in the Map, if either was not the Map is unchanged.
+func (m *Map[K, V]) SwapKeys(a, b K) (swapped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local.SwapKeys(a, b)
+}
+
+// ToLocal moves the contents of m into a new LocalMap in O(1). m is left empty
+// and ready for use.
+func (m *Map[K, V]) ToLocal() *LocalMap[K, V] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l := &LocalMap[K, V]{}
+	l.move(&m.local)
+	return l
+}
 
-	m.order[i], m.order[j] = m.order[j], m.order[i]
+// View returns a read-only View of the keys from index i up to but not
+// including index j, without copying them. Negative values of i and j index
+// from the end of the Map, out of range values are moved to the nearest end.
+func (m *Map[K, V]) View(i, j int) *View[K, V] {
+	return &View[K, V]{local: &m.local, mu: &m.mu, i: i, j: j}
 }
 
 // Ordered represents all orderable types.
@@ -249,16 +747,52 @@ type SortMap[K Ordered, V any] struct {
 	Map[K, V]
 }
 
+// NewSortMap returns an empty SortMap configured by opts.
+func NewSortMap[K Ordered, V any](opts ...Option) *SortMap[K, V] {
+	m := &SortMap[K, V]{}
+	m.local.init(opts)
+	return m
+}
+
+// Clone returns an independent copy of m with the same order and options, it
+// is copied atomically under a single read lock. Values which implement Cloner
+// are copied with their Clone method, other values are copied by assignment.
+func (m *SortMap[K, V]) Clone() *SortMap[K, V] {
+	return m.CloneFunc(cloneValue[V])
+}
+
+// CloneFunc returns an independent copy of m with the same order and options,
+// each value is copied by calling f. It is copied atomically under a single
+// read lock, f must not call any method on m.
+func (m *SortMap[K, V]) CloneFunc(f func(V) V) *SortMap[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c := &SortMap[K, V]{}
+	m.local.clone(&c.local, f)
+	return c
+}
+
+// Slice returns a new SortMap with the same options holding copies of the keys
+// from index i up to but not including index j, it is copied atomically under
+// a single read lock and values are copied by assignment. Negative values of i
+// and j index from the end of the SortMap, out of range values are moved to the
+// nearest end.
+func (m *SortMap[K, V]) Slice(i, j int) *SortMap[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c := &SortMap[K, V]{}
+	m.local.slice(&c.local, i, j)
+	return c
+}
+
 // Less returns true if the key at index i is less than the key at index j.
 func (m *SortMap[K, V]) Less(i, j int) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if i < 0 || i >= len(m.order) || j < 0 || j >= len(m.order) {
-		return false
-	}
-
-	return m.order[i] < m.order[j]
+	return less(&m.local, i, j)
 }
 
 // String formats the map for printing
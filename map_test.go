@@ -1,706 +1,3253 @@
 package ordered
 
 import (
+	"errors"
 	"fmt"
+	"iter"
 	"math/rand"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 )
 
-func TestDelete(t *testing.T) {
+// counter is a Cloner used to test deep copies.
+type counter struct {
+	n *int
+}
+
+func (c counter) Clone() counter {
+	n := *c.n
+	return counter{&n}
+}
+
+func TestClear(t *testing.T) {
+	for name, b := range backings {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap[string, int](WithBacking(b))
+			fill(&m.local, []string{"one", "two"}, map[string]int{"one": 1, "two": 2})
+			m.Clear()
+			checkContent(t, m, nil, nil)
+
+			m.Store("three", 3)
+			checkContent(t, m, []string{"three"}, map[string]int{"three": 3})
+			if m.local.opts.backing != b {
+				t.Errorf("Unexpected backing, wanted %d but got %d", b, m.local.opts.backing)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		backing       Backing
+	}{
+		"nil_clone": {},
+		"empty_clone": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+		},
+		"clone_three": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+		},
+		"clone_deque": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			backing:       Deque,
+		},
+		"clone_list": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			backing:       List,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := NewSortMap[string, int](WithBacking(test.backing))
+			fill(&m.local, test.startingOrder, test.startingMap)
+			wantOrder, wantMap := content(&m.local)
+
+			c := m.Clone()
+			checkContent(t, &c.Map, wantOrder, wantMap)
+			if c.local.opts != m.local.opts {
+				t.Errorf("Unexpected options, wanted %#v but got %#v", m.local.opts, c.local.opts)
+			}
+
+			c.StoreFirst("zero", 0)
+			c.Delete("two")
+			checkContent(t, &m.Map, wantOrder, wantMap)
+		})
+	}
+}
+
+func TestCloneFunc(t *testing.T) {
+	var m Map[string, []int]
+	m.Store("one", []int{1})
+	m.Store("two", []int{2, 2})
+
+	c := m.CloneFunc(func(v []int) []int {
+		return append([]int(nil), v...)
+	})
+	v, _ := c.Load("one")
+	v[0] = 11
+	if v, _ := m.Load("one"); v[0] != 1 {
+		t.Errorf("Modifying the clone's value modified the original, got %d", v[0])
+	}
+	checkContent(t, c, []string{"one", "two"}, map[string][]int{"one": {11}, "two": {2, 2}})
+}
+
+func TestCloneCloner(t *testing.T) {
+	var m Map[string, counter]
+	one := 1
+	m.Store("one", counter{&one})
+
+	c := m.Clone()
+	v, _ := c.Load("one")
+	*v.n = 11
+	if one != 1 {
+		t.Errorf("Modifying the clone's value modified the original, got %d", one)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	for name, b := range backings {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap[int, int](WithBacking(b))
+			for i := 0; i < 100; i++ {
+				m.Store(i, i)
+			}
+			for i := 5; i < 95; i++ {
+				m.Delete(i)
+			}
+			m.Compact()
+
+			checkContent(t, m, []int{0, 1, 2, 3, 4, 95, 96, 97, 98, 99}, map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4, 95: 95, 96: 96, 97: 97, 98: 98, 99: 99})
+			if d, ok := m.local.order.(*deque[int, int]); ok && len(d.buf) != 16 {
+				t.Errorf("Unexpected buffer size, wanted 16 but got %d", len(d.buf))
+			}
+		})
+	}
+}
+
+func TestCompactEmpty(t *testing.T) {
+	var m Map[int, int]
+	m.local.opts.backing = Deque
+	m.Compact()
+	checkContent(t, &m, nil, nil)
+
+	m.Store(1, 1)
+	m.Delete(1)
+	m.Compact()
+	checkContent(t, &m, []int{}, map[int]int{})
+	if d := m.local.order.(*deque[int, int]); d.buf != nil {
+		t.Errorf("Unexpected buffer size, wanted 0 but got %d", len(d.buf))
+	}
+
+	m.StoreFirst(2, 2)
+	checkContent(t, &m, []int{2}, map[int]int{2: 2})
+}
+
+// TestDequeClearsSlots checks that deleted entries are not held by the deque.
+func TestDequeClearsSlots(t *testing.T) {
+	m := NewMap[int, int](WithBacking(Deque))
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+	m.PopN(10)
+	m.PopN(-10)
+	for i := 20; i < 60; i++ {
+		m.Delete(i)
+	}
+
+	d := m.local.order.(*deque[int, int])
+	var held int
+	for _, e := range d.buf {
+		if e != nil {
+			held++
+		}
+	}
+	if held != d.len() {
+		t.Errorf("Deque holds %d entries but has length %d", held, d.len())
+	}
+}
+
+func TestCompareAndDelete(t *testing.T) {
 	for name, test := range map[string]struct {
 		startingOrder, wantOrder []string
 		startingMap, wantMap     map[string]int
 		key                      string
+		old                      int
+		wantDeleted              bool
 	}{
-		"nil_delete": {
-			key: "notakey",
-		},
-		"empty_delete": {
+		"empty": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
 			wantOrder:     []string{},
 			wantMap:       map[string]int{},
-			key:           "notakey",
+			key:           "one",
+			old:           1,
 		},
-		"delete_none": {
-			startingOrder: []string{"one"},
-			startingMap:   map[string]int{"one": 1},
-			wantOrder:     []string{"one"},
-			wantMap:       map[string]int{"one": 1},
-			key:           "notakey",
+		"missing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "four",
+			old:           0,
 		},
-		"delete_one": {
-			startingOrder: []string{"one"},
-			startingMap:   map[string]int{"one": 1},
-			wantOrder:     []string{},
-			wantMap:       map[string]int{},
+		"not_equal": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
 			key:           "one",
+			old:           2,
 		},
-		"delete_five": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"zero", "one", "two", "three", "four", "six", "seven", "eight", "nine"},
-			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "five",
+		"equal": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "two": 2, "three": 3},
+			key:           "one",
+			old:           1,
+			wantDeleted:   true,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			m.Delete(test.key)
-			if !reflect.DeepEqual(m.dirty, test.wantMap) {
-				t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", m.dirty, test.wantMap)
-			}
-			if !reflect.DeepEqual(m.order, test.wantOrder) {
-				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", m.order, test.wantOrder)
+			m := newSortMap(test.startingOrder, test.startingMap)
+			deleted := m.CompareAndDelete(test.key, test.old)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if deleted != test.wantDeleted {
+				t.Errorf("Unexpected deleted, wanted %t but got %t", test.wantDeleted, deleted)
 			}
 		})
 	}
 }
 
-func TestIndex(t *testing.T) {
+func TestCompareAndSwap(t *testing.T) {
 	for name, test := range map[string]struct {
-		startingOrder []string
-		startingMap   map[string]int
-		index         int
-		wantKey       string
-		wantValue     int
-		wantLoaded    bool
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		old, new                 int
+		wantSwapped              bool
 	}{
-		"nil_index": {
-			index:      0,
-			wantKey:    "",
-			wantValue:  0,
-			wantLoaded: false,
-		},
-		"empty_index": {
+		"empty": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
-			index:         0,
-			wantKey:       "",
-			wantValue:     0,
-			wantLoaded:    false,
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			key:           "one",
+			old:           1,
+			new:           10,
 		},
-		"index_zero": {
-			startingOrder: []string{"zero"},
-			startingMap:   map[string]int{"zero": 0},
-			index:         0,
-			wantKey:       "zero",
-			wantValue:     0,
-			wantLoaded:    true,
+		"missing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "four",
+			old:           0,
+			new:           10,
 		},
-		"index_three": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			index:         3,
-			wantKey:       "three",
-			wantValue:     3,
-			wantLoaded:    true,
+		"not_equal": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "one",
+			old:           2,
+			new:           10,
 		},
-		"index_out_of_range": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			index:         10,
-			wantKey:       "",
-			wantValue:     0,
-			wantLoaded:    false,
+		"equal": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+			key:           "one",
+			old:           1,
+			new:           10,
+			wantSwapped:   true,
 		},
-		"index_last": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			index:         -1,
-			wantKey:       "nine",
-			wantValue:     9,
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			swapped := m.CompareAndSwap(test.key, test.old, test.new)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if swapped != test.wantSwapped {
+				t.Errorf("Unexpected swapped, wanted %t but got %t", test.wantSwapped, swapped)
+			}
+		})
+	}
+}
+
+func TestCompareAndSwapIncomparable(t *testing.T) {
+	m := NewMap[string, any]()
+	m.Store("one", []int{1})
+	m.Store("two", 2)
+
+	if !m.CompareAndSwap("two", 2, []int{2}) {
+		t.Error("Comparable value should have been swapped")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Comparing an incomparable value should panic")
+		}
+	}()
+	m.CompareAndSwap("one", []int{1}, 1)
+}
+
+func TestCompute(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		op                       Op
+		wantOld                  int
+		wantLoaded               bool
+		wantActual               int
+		wantPresent              bool
+	}{
+		"absent_keep": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "three",
+			value:         3,
+			op:            OpKeep,
+		},
+		"absent_store": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			value:         3,
+			op:            OpStore,
+			wantActual:    3,
+			wantPresent:   true,
+		},
+		"absent_delete": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "three",
+			value:         3,
+			op:            OpDelete,
+		},
+		"present_keep": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "one",
+			value:         10,
+			op:            OpKeep,
+			wantOld:       1,
 			wantLoaded:    true,
+			wantActual:    1,
+			wantPresent:   true,
 		},
-		"index_zero_backwards": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			index:         -10,
-			wantKey:       "zero",
-			wantValue:     0,
+		"present_store": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2},
+			key:           "one",
+			value:         10,
+			op:            OpStore,
+			wantOld:       1,
 			wantLoaded:    true,
+			wantActual:    10,
+			wantPresent:   true,
 		},
-		"index_out_of_range_backwards": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			index:         -11,
-			wantKey:       "",
-			wantValue:     0,
-			wantLoaded:    false,
+		"present_delete": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "two"},
+			wantMap:       map[string]int{"zero": 0, "two": 2},
+			key:           "one",
+			value:         10,
+			op:            OpDelete,
+			wantOld:       1,
+			wantLoaded:    true,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			key, value, loaded := m.Index(test.index)
-			if key != test.wantKey {
-				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
-			}
-			if value != test.wantValue {
-				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
-			}
-			if loaded != test.wantLoaded {
-				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
+			m := newSortMap(test.startingOrder, test.startingMap)
+			actual, present := m.Compute(test.key, func(old int, loaded bool) (int, Op) {
+				if old != test.wantOld || loaded != test.wantLoaded {
+					t.Errorf("Unexpected arguments, wanted %d, %t but got %d, %t", test.wantOld, test.wantLoaded, old, loaded)
+				}
+				return test.value, test.op
+			})
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if actual != test.wantActual || present != test.wantPresent {
+				t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantActual, test.wantPresent, actual, present)
 			}
 		})
 	}
 }
 
-func TestLen(t *testing.T) {
+func TestComputeIfAbsent(t *testing.T) {
 	for name, test := range map[string]struct {
-		startingOrder []string
-		startingMap   map[string]int
-		want          int
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		op                       Op
+		wantCalled               bool
+		wantActual               int
+		wantPresent              bool
 	}{
-		"nil": {
-			want: 0,
+		"absent_keep": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "three",
+			value:         3,
+			op:            OpKeep,
+			wantCalled:    true,
 		},
-		"empty": {
-			startingOrder: []string{},
-			startingMap:   map[string]int{},
-			want:          0,
-		},
-		"one": {
-			startingOrder: []string{"one"},
-			startingMap:   map[string]int{"one": 1},
-			want:          1,
+		"absent_store": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			value:         3,
+			op:            OpStore,
+			wantCalled:    true,
+			wantActual:    3,
+			wantPresent:   true,
 		},
-		"ten": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			want:          10,
+		"present": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "one",
+			value:         10,
+			op:            OpStore,
+			wantActual:    1,
+			wantPresent:   true,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
+			m := newSortMap(test.startingOrder, test.startingMap)
+			var called bool
+			actual, present := m.ComputeIfAbsent(test.key, func() (int, Op) {
+				called = true
+				return test.value, test.op
+			})
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if actual != test.wantActual || present != test.wantPresent {
+				t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantActual, test.wantPresent, actual, present)
 			}
-			ln := m.Len()
-			if ln != test.want {
-				t.Errorf("Unexpected length, wanted %d but got %d", test.want, ln)
+			if called != test.wantCalled {
+				t.Errorf("Unexpected called, wanted %t but got %t", test.wantCalled, called)
 			}
 		})
 	}
 }
 
-func TestLess(t *testing.T) {
+func TestComputeIfPresent(t *testing.T) {
 	for name, test := range map[string]struct {
-		startingOrder []int
-		startingMap   map[int]string
-		i, j          int
-		want          bool
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		op                       Op
+		wantCalled               bool
+		wantActual               int
+		wantPresent              bool
 	}{
-		"nill_less": {
-			i:    0,
-			j:    1,
-			want: false,
+		"absent": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "three",
+			value:         3,
+			op:            OpStore,
 		},
-		"empty_less": {
-			startingOrder: []int{},
-			startingMap:   map[int]string{},
-			i:             0,
-			j:             1,
-			want:          false,
+		"present_store": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2},
+			key:           "one",
+			value:         10,
+			op:            OpStore,
+			wantCalled:    true,
+			wantActual:    10,
+			wantPresent:   true,
 		},
-		"less": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             0,
-			j:             1,
-			want:          true,
-		},
-		"more": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             1,
-			j:             0,
-			want:          false,
-		},
-		"equal": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             1,
-			j:             1,
-			want:          false,
-		},
-		"i_low": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             -1,
-			j:             1,
-			want:          false,
-		},
-		"i_high": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             3,
-			j:             1,
-			want:          false,
-		},
-		"j_low": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             1,
-			j:             -1,
-			want:          false,
-		},
-		"j_high": {
-			startingOrder: []int{1, 2, 3},
-			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
-			i:             1,
-			j:             3,
-			want:          false,
+		"present_delete": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "two"},
+			wantMap:       map[string]int{"zero": 0, "two": 2},
+			key:           "one",
+			value:         10,
+			op:            OpDelete,
+			wantCalled:    true,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[int, string]{
-				Map[int, string]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
+			m := newSortMap(test.startingOrder, test.startingMap)
+			var called bool
+			actual, present := m.ComputeIfPresent(test.key, func(old int) (int, Op) {
+				called = true
+				return test.value, test.op
+			})
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if actual != test.wantActual || present != test.wantPresent {
+				t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantActual, test.wantPresent, actual, present)
 			}
-			less := m.Less(test.i, test.j)
-			if less != test.want {
-				t.Errorf("Unexpected less, wanted %t but got %t", test.want, less)
+			if called != test.wantCalled {
+				t.Errorf("Unexpected called, wanted %t but got %t", test.wantCalled, called)
 			}
 		})
 	}
 }
 
-func TestLoad(t *testing.T) {
-	for name, test := range map[string]struct {
-		startingOrder []string
-		startingMap   map[string]int
-		key           string
-		wantValue     int
-		wantOK        bool
-	}{
-		"nil_load": {
-			key:       "notakey",
-			wantValue: 0,
-			wantOK:    false,
-		},
-		"empty_load": {
-			startingOrder: []string{},
-			startingMap:   map[string]int{},
-			key:           "notakey",
-			wantValue:     0,
-			wantOK:        false,
-		},
-		"load_none": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "notakey",
-			wantValue:     0,
-			wantOK:        false,
-		},
-		"load_seven": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "seven",
-			wantValue:     7,
-			wantOK:        true,
-		},
-		"load_zero": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "zero",
-			wantValue:     0,
-			wantOK:        true,
-		},
-	} {
-		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			value, ok := m.Load(test.key)
-			if value != test.wantValue {
-				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
-			}
-			if ok != test.wantOK {
-				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantOK, ok)
+func TestComputeConcurrent(t *testing.T) {
+	m := NewMap[string, int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Compute("count", func(old int, loaded bool) (int, Op) {
+					return old + 1, OpStore
+				})
 			}
-		})
+		}()
 	}
+	wg.Wait()
+	checkContent(t, m, []string{"count"}, map[string]int{"count": 8000})
 }
 
-func TestLoadAndDelete(t *testing.T) {
+func TestDelete(t *testing.T) {
 	for name, test := range map[string]struct {
 		startingOrder, wantOrder []string
 		startingMap, wantMap     map[string]int
 		key                      string
-		wantValue                int
-		wantLoaded               bool
 	}{
-		"nil_loadAndDelete": {
-			key:        "notakey",
-			wantValue:  0,
-			wantLoaded: false,
+		"nil_delete": {
+			key: "notakey",
 		},
-		"empty_loadAndDelete": {
+		"empty_delete": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
 			wantOrder:     []string{},
 			wantMap:       map[string]int{},
 			key:           "notakey",
-			wantValue:     0,
-			wantLoaded:    false,
 		},
-		"loadAndDelete_none": {
+		"delete_none": {
 			startingOrder: []string{"one"},
 			startingMap:   map[string]int{"one": 1},
 			wantOrder:     []string{"one"},
 			wantMap:       map[string]int{"one": 1},
 			key:           "notakey",
-			wantValue:     0,
-			wantLoaded:    false,
 		},
-		"loadAndDelete_one": {
+		"delete_one": {
 			startingOrder: []string{"one"},
 			startingMap:   map[string]int{"one": 1},
 			wantOrder:     []string{},
 			wantMap:       map[string]int{},
 			key:           "one",
-			wantValue:     1,
-			wantLoaded:    true,
 		},
-		"loadAndDelete_five": {
+		"delete_five": {
 			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
 			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
 			wantOrder:     []string{"zero", "one", "two", "three", "four", "six", "seven", "eight", "nine"},
 			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "six": 6, "seven": 7, "eight": 8, "nine": 9},
 			key:           "five",
-			wantValue:     5,
-			wantLoaded:    true,
-		},
-		"loadAndDelete_zero": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			wantMap:       map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "zero",
-			wantValue:     0,
-			wantLoaded:    true,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			value, loaded := m.LoadAndDelete(test.key)
-			if !reflect.DeepEqual(m.dirty, test.wantMap) {
-				t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", m.dirty, test.wantMap)
-			}
-			if !reflect.DeepEqual(m.order, test.wantOrder) {
-				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", m.order, test.wantOrder)
-			}
-			if value != test.wantValue {
-				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
-			}
-			if loaded != test.wantLoaded {
-				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
-			}
+			m := newSortMap(test.startingOrder, test.startingMap)
+			m.Delete(test.key)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
 		})
 	}
 }
 
-func TestLoadAndDeleteFirst(t *testing.T) {
+func TestDeleteAll(t *testing.T) {
 	for name, test := range map[string]struct {
 		startingOrder, wantOrder []string
 		startingMap, wantMap     map[string]int
-		wantKey                  string
-		wantValue                int
-		wantLoaded               bool
+		keys                     []string
+		wantDeleted              int
 	}{
-		"nil_load": {
-			wantKey:    "",
-			wantValue:  0,
-			wantLoaded: false,
-		},
-		"empty_load": {
+		"empty": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
 			wantOrder:     []string{},
 			wantMap:       map[string]int{},
-			wantKey:       "",
-			wantValue:     0,
-			wantLoaded:    false,
+			keys:          []string{"one"},
+			wantDeleted:   0,
 		},
-		"loadAndDelete_zero": {
-			startingOrder: []string{"zero"},
-			startingMap:   map[string]int{"zero": 0},
+		"none": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			keys:          []string{},
+			wantDeleted:   0,
+		},
+		"missing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			keys:          []string{"four", "five"},
+			wantDeleted:   0,
+		},
+		"some": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two"},
+			wantMap:       map[string]int{"zero": 0, "two": 2},
+			keys:          []string{"three", "four", "one"},
+			wantDeleted:   2,
+		},
+		"duplicate": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "two": 2, "three": 3},
+			keys:          []string{"one", "one"},
+			wantDeleted:   1,
+		},
+		"all": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
 			wantOrder:     []string{},
 			wantMap:       map[string]int{},
-			wantKey:       "zero",
-			wantValue:     0,
-			wantLoaded:    true,
-		},
-		"loadAndDelete_one": {
-			startingOrder: []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			wantMap:       map[string]int{"two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantKey:       "one",
-			wantValue:     1,
-			wantLoaded:    true,
+			keys:          []string{"two", "zero", "three", "one"},
+			wantDeleted:   4,
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			key, value, ok := m.LoadAndDeleteFirst()
-			if key != test.wantKey {
-				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
-			}
-			if value != test.wantValue {
-				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
-			}
-			if ok != test.wantLoaded {
-				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			m := newSortMap(test.startingOrder, test.startingMap)
+			deleted := m.DeleteAll(test.keys...)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if deleted != test.wantDeleted {
+				t.Errorf("Unexpected deleted, wanted %d but got %d", test.wantDeleted, deleted)
 			}
 		})
 	}
 }
 
-func TestLoadAndDeleteLast(t *testing.T) {
+func TestDeleteAt(t *testing.T) {
 	for name, test := range map[string]struct {
 		startingOrder, wantOrder []string
 		startingMap, wantMap     map[string]int
+		n                        int
 		wantKey                  string
 		wantValue                int
-		wantLoaded               bool
+		wantDeleted              bool
 	}{
-		"nil_load": {
-			wantKey:    "",
-			wantValue:  0,
-			wantLoaded: false,
-		},
-		"empty_load": {
+		"empty": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
 			wantOrder:     []string{},
 			wantMap:       map[string]int{},
-			wantKey:       "",
-			wantValue:     0,
-			wantLoaded:    false,
+			n:             0,
 		},
-		"loadAndDelete_zero": {
-			startingOrder: []string{"zero"},
-			startingMap:   map[string]int{"zero": 0},
-			wantOrder:     []string{},
-			wantMap:       map[string]int{},
+		"out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             4,
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "three"},
+			wantMap:       map[string]int{"one": 1, "two": 2, "three": 3},
+			n:             0,
 			wantKey:       "zero",
 			wantValue:     0,
-			wantLoaded:    true,
+			wantDeleted:   true,
 		},
-		"loadAndDelete_nine": {
-			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight"},
-			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8},
-			wantKey:       "nine",
-			wantValue:     9,
-			wantLoaded:    true,
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "three": 3},
+			n:             2,
+			wantKey:       "two",
+			wantValue:     2,
+			wantDeleted:   true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			n:             -1,
+			wantKey:       "three",
+			wantValue:     3,
+			wantDeleted:   true,
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "two": 2, "three": 3},
+			n:             -3,
+			wantKey:       "one",
+			wantValue:     1,
+			wantDeleted:   true,
+		},
+		"negative_out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             -5,
 		},
 	} {
-		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			key, value, ok := m.LoadAndDeleteLast()
-			if key != test.wantKey {
-				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
-			}
-			if value != test.wantValue {
-				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
-			}
-			if ok != test.wantLoaded {
-				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
-			}
-		})
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				key, value, deleted := m.DeleteAt(test.n)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if key != test.wantKey || value != test.wantValue || deleted != test.wantDeleted {
+					t.Errorf("Unexpected result, wanted %q, %d, %t but got %q, %d, %t", test.wantKey, test.wantValue, test.wantDeleted, key, value, deleted)
+				}
+			})
+		}
 	}
 }
 
-func TestLoadOrStore(t *testing.T) {
+func TestDeleteRange(t *testing.T) {
 	for name, test := range map[string]struct {
 		startingOrder, wantOrder []string
 		startingMap, wantMap     map[string]int
-		key                      string
-		value                    int
-		wantValue                int
-		wantLoaded               bool
+		i, j                     int
+		wantDeleted              int
 	}{
-		"nil_Store": {
-			wantOrder:  []string{"one"},
-			wantMap:    map[string]int{"one": 1},
-			key:        "one",
-			value:      1,
-			wantValue:  1,
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             0,
+			j:             2,
+		},
+		"all": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             0,
+			j:             4,
+			wantDeleted:   4,
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "three"},
+			wantMap:       map[string]int{"one": 1, "two": 2, "three": 3},
+			i:             0,
+			j:             1,
+			wantDeleted:   1,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "three"},
+			wantMap:       map[string]int{"zero": 0, "three": 3},
+			i:             1,
+			j:             3,
+			wantDeleted:   2,
+		},
+		"last_two": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one"},
+			wantMap:       map[string]int{"zero": 0, "one": 1},
+			i:             -2,
+			j:             4,
+			wantDeleted:   2,
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "three"},
+			wantMap:       map[string]int{"zero": 0, "three": 3},
+			i:             -3,
+			j:             -1,
+			wantDeleted:   2,
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			i:             2,
+			j:             2,
+		},
+		"backwards": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			i:             3,
+			j:             1,
+		},
+		"too_wide": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             -10,
+			j:             10,
+			wantDeleted:   4,
+		},
+		"out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			i:             4,
+			j:             10,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				deleted := m.DeleteRange(test.i, test.j)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if deleted != test.wantDeleted {
+					t.Errorf("Unexpected deleted, wanted %d but got %d", test.wantDeleted, deleted)
+				}
+			})
+		}
+	}
+}
+
+func TestGrow(t *testing.T) {
+	m := NewMap[int, int](WithBacking(Deque), WithCapacity(10))
+	d := m.local.order.(*deque[int, int])
+	if len(d.buf) != 16 {
+		t.Errorf("Unexpected buffer size, wanted 16 but got %d", len(d.buf))
+	}
+
+	m.Store(1, 1)
+	m.Grow(100)
+	if len(d.buf) != 128 {
+		t.Errorf("Unexpected buffer size, wanted 128 but got %d", len(d.buf))
+	}
+	buf := &d.buf[0]
+	for i := 2; i <= 101; i++ {
+		m.StoreFirst(i, i)
+	}
+	if buf != &d.buf[0] {
+		t.Error("Buffer was reallocated after Grow")
+	}
+	if m.Len() != 101 {
+		t.Errorf("Unexpected length, wanted 101 but got %d", m.Len())
+	}
+
+	dirty := reflect.ValueOf(m.local.dirty).Pointer()
+	m.Grow(0)
+	m.Grow(-1)
+	if reflect.ValueOf(m.local.dirty).Pointer() != dirty {
+		t.Error("Index of keys was rebuilt by Grow(0)")
+	}
+	m.Grow(1)
+	dirty = reflect.ValueOf(m.local.dirty).Pointer()
+	m.Grow(50)
+	if reflect.ValueOf(m.local.dirty).Pointer() != dirty {
+		t.Error("Index of keys was rebuilt by Grow when it had room")
+	}
+
+	var z Map[int, int]
+	z.Grow(3)
+	z.Store(1, 1)
+	checkContent(t, &z, []int{1}, map[int]int{1: 1})
+}
+
+func TestIndex(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		index         int
+		wantKey       string
+		wantValue     int
+		wantLoaded    bool
+	}{
+		"nil_index": {
+			index:      0,
+			wantKey:    "",
+			wantValue:  0,
 			wantLoaded: false,
 		},
-		"empty_Store": {
+		"empty_index": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
-			wantOrder:     []string{"one"},
-			wantMap:       map[string]int{"one": 1},
-			key:           "one",
-			value:         1,
-			wantValue:     1,
+			index:         0,
+			wantKey:       "",
+			wantValue:     0,
 			wantLoaded:    false,
 		},
-		"store_ten": {
+		"index_zero": {
+			startingOrder: []string{"zero"},
+			startingMap:   map[string]int{"zero": 0},
+			index:         0,
+			wantKey:       "zero",
+			wantValue:     0,
+			wantLoaded:    true,
+		},
+		"index_three": {
 			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
 			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"},
-			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10},
-			key:           "ten",
-			value:         10,
-			wantValue:     10,
+			index:         3,
+			wantKey:       "three",
+			wantValue:     3,
+			wantLoaded:    true,
+		},
+		"index_out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			index:         10,
+			wantKey:       "",
+			wantValue:     0,
 			wantLoaded:    false,
 		},
-		"load_nine": {
+		"index_last": {
 			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
 			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "nine",
-			value:         10,
+			index:         -1,
+			wantKey:       "nine",
 			wantValue:     9,
 			wantLoaded:    true,
 		},
-		"load_zero": {
+		"index_zero_backwards": {
 			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
 			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
-			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
-			key:           "zero",
-			value:         10,
+			index:         -10,
+			wantKey:       "zero",
 			wantValue:     0,
 			wantLoaded:    true,
 		},
+		"index_out_of_range_backwards": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			index:         -11,
+			wantKey:       "",
+			wantValue:     0,
+			wantLoaded:    false,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			value, ok := m.LoadOrStore(test.key, test.value)
-			if !reflect.DeepEqual(m.dirty, test.wantMap) {
-				t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", m.dirty, test.wantMap)
-			}
-			if !reflect.DeepEqual(m.order, test.wantOrder) {
-				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", m.order, test.wantOrder)
+			m := newSortMap(test.startingOrder, test.startingMap)
+			key, value, loaded := m.Index(test.index)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
 			}
 			if value != test.wantValue {
 				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
 			}
-			if ok != test.wantLoaded {
-				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			if loaded != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
 			}
 		})
 	}
 }
 
-func TestRange(t *testing.T) {
-	type row struct {
-		key   string
-		value int
-	}
+func TestIndexOf(t *testing.T) {
 	for name, test := range map[string]struct {
 		startingOrder []string
 		startingMap   map[string]int
-		endOn         int
-		mutateOn      int
-		wantRows      []row
+		key           string
+		wantIndex     int
+		wantOK        bool
 	}{
-		"nil_range": {
-			wantRows: []row{},
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			key:           "one",
+			wantIndex:     0,
 		},
-		"empty_range": {
+		"missing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "four",
+			wantIndex:     0,
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "zero",
+			wantIndex:     0,
+			wantOK:        true,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "two",
+			wantIndex:     2,
+			wantOK:        true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			wantIndex:     3,
+			wantOK:        true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				n, ok := m.IndexOf(test.key)
+				if n != test.wantIndex || ok != test.wantOK {
+					t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantIndex, test.wantOK, n, ok)
+				}
+			})
+		}
+	}
+}
+
+// TestIndexOfUpdates checks that IndexOf follows keys as the order changes.
+func TestIndexOfUpdates(t *testing.T) {
+	for name, b := range backings {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap[int, int](WithBacking(b))
+			for i := 0; i < 20; i++ {
+				m.Store(i, i)
+				m.StoreFirst(-i-1, i)
+			}
+			m.Swap(3, 30)
+			m.Delete(7)
+			m.LoadAndDeleteFirst()
+			m.LoadAndDeleteLast()
+			m.PopN(2)
+			m.InsertAt(5, 100, 100)
+
+			m.Range(func(index, key, value int) bool {
+				if n, ok := m.IndexOf(key); n != index || !ok {
+					t.Errorf("Unexpected index of key %d, wanted %d, true but got %d, %t", key, index, n, ok)
+				}
+				return true
+			})
+		})
+	}
+}
+
+func TestInsertAt(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		n                        int
+		key                      string
+		value                    int
+		wantAdded                bool
+	}{
+		"empty_insert_last": {
 			startingOrder: []string{},
 			startingMap:   map[string]int{},
-			wantRows:      []row{},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			n:             -1,
+			key:           "one",
+			value:         1,
+			wantAdded:     true,
 		},
-		"range": {
-			startingOrder: []string{"one", "two", "three"},
-			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
-			wantRows:      []row{{"one", 1}, {"two", 2}, {"three", 3}},
+		"insert_middle": {
+			startingOrder: []string{"zero", "one", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             2,
+			key:           "two",
+			value:         2,
+			wantAdded:     true,
+		},
+		"insert_end": {
+			startingOrder: []string{"zero", "one"},
+			startingMap:   map[string]int{"zero": 0, "one": 1},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			n:             2,
+			key:           "two",
+			value:         2,
+			wantAdded:     true,
+		},
+		"insert_negative": {
+			startingOrder: []string{"zero", "one", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             -2,
+			key:           "two",
+			value:         2,
+			wantAdded:     true,
+		},
+		"insert_too_high": {
+			startingOrder: []string{"zero", "one"},
+			startingMap:   map[string]int{"zero": 0, "one": 1},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			n:             10,
+			key:           "two",
+			value:         2,
+			wantAdded:     true,
+		},
+		"insert_too_low": {
+			startingOrder: []string{"zero", "one"},
+			startingMap:   map[string]int{"zero": 0, "one": 1},
+			wantOrder:     []string{"two", "zero", "one"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			n:             -10,
+			key:           "two",
+			value:         2,
+			wantAdded:     true,
+		},
+		"move_back": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			wantMap:       map[string]int{"zero": 10, "one": 1, "two": 2, "three": 3},
+			n:             2,
+			key:           "zero",
+			value:         10,
+		},
+		"move_forward": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             0,
+			key:           "three",
+			value:         3,
+		},
+		"move_last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three", "one"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             -1,
+			key:           "one",
+			value:         1,
+		},
+		"move_nowhere": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             1,
+			key:           "one",
+			value:         1,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				added := m.InsertAt(test.n, test.key, test.value)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if added != test.wantAdded {
+					t.Errorf("Unexpected added, wanted %t but got %t", test.wantAdded, added)
+				}
+			})
+		}
+	}
+}
+
+func TestKeepFirst(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		n                        int
+		wantKeys                 []string
+		wantValues               []int
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             2,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             0,
+			wantKeys:      []string{"zero", "one", "two", "three"},
+			wantValues:    []int{0, 1, 2, 3},
+		},
+		"some": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero"},
+			wantMap:       map[string]int{"zero": 0},
+			n:             1,
+			wantKeys:      []string{"one", "two", "three"},
+			wantValues:    []int{1, 2, 3},
+		},
+		"all": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             4,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"too_many": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             10,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             -1,
+			wantKeys:      []string{"zero", "one", "two", "three"},
+			wantValues:    []int{0, 1, 2, 3},
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				keys, values := m.KeepFirst(test.n)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if !reflect.DeepEqual(keys, test.wantKeys) {
+					t.Errorf("Unexpected keys\nactual: %#v\nwant  : %#v", keys, test.wantKeys)
+				}
+				if !reflect.DeepEqual(values, test.wantValues) {
+					t.Errorf("Unexpected values\nactual: %#v\nwant  : %#v", values, test.wantValues)
+				}
+			})
+		}
+	}
+}
+
+func TestKeepLast(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		n                        int
+		wantKeys                 []string
+		wantValues               []int
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             2,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             0,
+			wantKeys:      []string{"zero", "one", "two", "three"},
+			wantValues:    []int{0, 1, 2, 3},
+		},
+		"some": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three"},
+			wantMap:       map[string]int{"three": 3},
+			n:             1,
+			wantKeys:      []string{"zero", "one", "two"},
+			wantValues:    []int{0, 1, 2},
+		},
+		"all": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             4,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"too_many": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             10,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             -1,
+			wantKeys:      []string{"zero", "one", "two", "three"},
+			wantValues:    []int{0, 1, 2, 3},
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				keys, values := m.KeepLast(test.n)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if !reflect.DeepEqual(keys, test.wantKeys) {
+					t.Errorf("Unexpected keys\nactual: %#v\nwant  : %#v", keys, test.wantKeys)
+				}
+				if !reflect.DeepEqual(values, test.wantValues) {
+					t.Errorf("Unexpected values\nactual: %#v\nwant  : %#v", values, test.wantValues)
+				}
+			})
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		want          int
+	}{
+		"nil": {
+			want: 0,
+		},
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			want:          0,
+		},
+		"one": {
+			startingOrder: []string{"one"},
+			startingMap:   map[string]int{"one": 1},
+			want:          1,
+		},
+		"ten": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			want:          10,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			ln := m.Len()
+			if ln != test.want {
+				t.Errorf("Unexpected length, wanted %d but got %d", test.want, ln)
+			}
+		})
+	}
+}
+
+func TestLess(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []int
+		startingMap   map[int]string
+		i, j          int
+		want          bool
+	}{
+		"nill_less": {
+			i:    0,
+			j:    1,
+			want: false,
+		},
+		"empty_less": {
+			startingOrder: []int{},
+			startingMap:   map[int]string{},
+			i:             0,
+			j:             1,
+			want:          false,
+		},
+		"less": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             0,
+			j:             1,
+			want:          true,
+		},
+		"more": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             1,
+			j:             0,
+			want:          false,
+		},
+		"equal": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             1,
+			j:             1,
+			want:          false,
+		},
+		"i_low": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             -1,
+			j:             1,
+			want:          false,
+		},
+		"i_high": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             3,
+			j:             1,
+			want:          false,
+		},
+		"j_low": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             1,
+			j:             -1,
+			want:          false,
+		},
+		"j_high": {
+			startingOrder: []int{1, 2, 3},
+			startingMap:   map[int]string{1: "one", 2: "two", 3: "three"},
+			i:             1,
+			j:             3,
+			want:          false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			less := m.Less(test.i, test.j)
+			if less != test.want {
+				t.Errorf("Unexpected less, wanted %t but got %t", test.want, less)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		key           string
+		wantValue     int
+		wantOK        bool
+	}{
+		"nil_load": {
+			key:       "notakey",
+			wantValue: 0,
+			wantOK:    false,
+		},
+		"empty_load": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			key:           "notakey",
+			wantValue:     0,
+			wantOK:        false,
+		},
+		"load_none": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "notakey",
+			wantValue:     0,
+			wantOK:        false,
+		},
+		"load_seven": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "seven",
+			wantValue:     7,
+			wantOK:        true,
+		},
+		"load_zero": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "zero",
+			wantValue:     0,
+			wantOK:        true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			value, ok := m.Load(test.key)
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if ok != test.wantOK {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantOK, ok)
+			}
+		})
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		keys          []string
+		wantValues    []int
+		wantLoaded    []bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			keys:          []string{"one"},
+			wantValues:    []int{0},
+			wantLoaded:    []bool{false},
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			keys:          []string{},
+			wantValues:    []int{},
+			wantLoaded:    []bool{},
+		},
+		"some": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			keys:          []string{"three", "four", "one"},
+			wantValues:    []int{3, 0, 1},
+			wantLoaded:    []bool{true, false, true},
+		},
+		"duplicate": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			keys:          []string{"one", "one"},
+			wantValues:    []int{1, 1},
+			wantLoaded:    []bool{true, true},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			values, loaded := m.LoadAll(test.keys...)
+			checkContent(t, &m.Map, test.startingOrder, test.startingMap)
+			if !reflect.DeepEqual(values, test.wantValues) {
+				t.Errorf("Unexpected values\nactual: %#v\nwant  : %#v", values, test.wantValues)
+			}
+			if !reflect.DeepEqual(loaded, test.wantLoaded) {
+				t.Errorf("Unexpected loaded\nactual: %#v\nwant  : %#v", loaded, test.wantLoaded)
+			}
+		})
+	}
+}
+
+func TestLoadAndDelete(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		wantValue                int
+		wantLoaded               bool
+	}{
+		"nil_loadAndDelete": {
+			key:        "notakey",
+			wantValue:  0,
+			wantLoaded: false,
+		},
+		"empty_loadAndDelete": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			key:           "notakey",
+			wantValue:     0,
+			wantLoaded:    false,
+		},
+		"loadAndDelete_none": {
+			startingOrder: []string{"one"},
+			startingMap:   map[string]int{"one": 1},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			key:           "notakey",
+			wantValue:     0,
+			wantLoaded:    false,
+		},
+		"loadAndDelete_one": {
+			startingOrder: []string{"one"},
+			startingMap:   map[string]int{"one": 1},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			key:           "one",
+			wantValue:     1,
+			wantLoaded:    true,
+		},
+		"loadAndDelete_five": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"zero", "one", "two", "three", "four", "six", "seven", "eight", "nine"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "five",
+			wantValue:     5,
+			wantLoaded:    true,
+		},
+		"loadAndDelete_zero": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			wantMap:       map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "zero",
+			wantValue:     0,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			value, loaded := m.LoadAndDelete(test.key)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if loaded != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
+			}
+		})
+	}
+}
+
+func TestLoadAndDeleteAt(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		n                        int
+		wantKey                  string
+		wantValue                int
+		wantLoaded               bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             0,
+		},
+		"out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             4,
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "three"},
+			wantMap:       map[string]int{"one": 1, "two": 2, "three": 3},
+			n:             0,
+			wantKey:       "zero",
+			wantValue:     0,
+			wantLoaded:    true,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "three": 3},
+			n:             2,
+			wantKey:       "two",
+			wantValue:     2,
+			wantLoaded:    true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			n:             -1,
+			wantKey:       "three",
+			wantValue:     3,
+			wantLoaded:    true,
+		},
+		"negative_out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             -5,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				key, value, loaded := m.LoadAndDeleteAt(test.n)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if key != test.wantKey || value != test.wantValue || loaded != test.wantLoaded {
+					t.Errorf("Unexpected result, wanted %q, %d, %t but got %q, %d, %t", test.wantKey, test.wantValue, test.wantLoaded, key, value, loaded)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadAndDeleteFirst(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		wantKey                  string
+		wantValue                int
+		wantLoaded               bool
+	}{
+		"nil_load": {
+			wantKey:    "",
+			wantValue:  0,
+			wantLoaded: false,
+		},
+		"empty_load": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			wantKey:       "",
+			wantValue:     0,
+			wantLoaded:    false,
+		},
+		"loadAndDelete_zero": {
+			startingOrder: []string{"zero"},
+			startingMap:   map[string]int{"zero": 0},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			wantKey:       "zero",
+			wantValue:     0,
+			wantLoaded:    true,
+		},
+		"loadAndDelete_one": {
+			startingOrder: []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			wantMap:       map[string]int{"two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantKey:       "one",
+			wantValue:     1,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			key, value, ok := m.LoadAndDeleteFirst()
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
+			}
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if ok != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			}
+		})
+	}
+}
+
+func TestLoadAndDeleteLast(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		wantKey                  string
+		wantValue                int
+		wantLoaded               bool
+	}{
+		"nil_load": {
+			wantKey:    "",
+			wantValue:  0,
+			wantLoaded: false,
+		},
+		"empty_load": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			wantKey:       "",
+			wantValue:     0,
+			wantLoaded:    false,
+		},
+		"loadAndDelete_zero": {
+			startingOrder: []string{"zero"},
+			startingMap:   map[string]int{"zero": 0},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			wantKey:       "zero",
+			wantValue:     0,
+			wantLoaded:    true,
+		},
+		"loadAndDelete_nine": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8},
+			wantKey:       "nine",
+			wantValue:     9,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			key, value, ok := m.LoadAndDeleteLast()
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
+			}
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if ok != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			}
+		})
+	}
+}
+
+func TestLoadAndStore(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		wantPrevious             int
+		wantLoaded               bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			key:           "one",
+			value:         1,
+		},
+		"new": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			value:         3,
+		},
+		"existing": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2},
+			key:           "one",
+			value:         10,
+			wantPrevious:  1,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			previous, loaded := m.LoadAndStore(test.key, test.value)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if previous != test.wantPrevious || loaded != test.wantLoaded {
+				t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantPrevious, test.wantLoaded, previous, loaded)
+			}
+		})
+	}
+}
+
+func TestLoadAndStoreAt(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		n                        int
+		key                      string
+		value                    int
+		wantPrevious             int
+		wantLoaded               bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			n:             3,
+			key:           "one",
+			value:         1,
+		},
+		"new": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "three", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             1,
+			key:           "three",
+			value:         3,
+		},
+		"new_negative": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "three", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			n:             -2,
+			key:           "three",
+			value:         3,
+		},
+		"existing": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2},
+			n:             0,
+			key:           "one",
+			value:         10,
+			wantPrevious:  1,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			previous, loaded := m.LoadAndStoreAt(test.n, test.key, test.value)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if previous != test.wantPrevious || loaded != test.wantLoaded {
+				t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantPrevious, test.wantLoaded, previous, loaded)
+			}
+		})
+	}
+}
+
+func TestLoadAndStoreFirst(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		wantPrevious             int
+		wantLoaded               bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			key:           "one",
+			value:         1,
+		},
+		"new": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"three", "zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			value:         3,
+		},
+		"existing": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2},
+			key:           "one",
+			value:         10,
+			wantPrevious:  1,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			previous, loaded := m.LoadAndStoreFirst(test.key, test.value)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if previous != test.wantPrevious || loaded != test.wantLoaded {
+				t.Errorf("Unexpected result, wanted %d, %t but got %d, %t", test.wantPrevious, test.wantLoaded, previous, loaded)
+			}
+		})
+	}
+}
+
+var errBoom = errors.New("boom")
+
+func TestLoadOrCompute(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		err                      error
+		wantActual               int
+		wantLoaded               bool
+		wantErr                  error
+	}{
+		"present": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "one",
+			value:         10,
+			wantActual:    1,
+			wantLoaded:    true,
+		},
+		"present_error": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "one",
+			value:         10,
+			err:           errBoom,
+			wantActual:    1,
+			wantLoaded:    true,
+		},
+		"absent": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			value:         3,
+			wantActual:    3,
+		},
+		"absent_error": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+			key:           "three",
+			value:         3,
+			err:           errBoom,
+			wantActual:    0,
+			wantErr:       errBoom,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			actual, loaded, err := m.LoadOrCompute(test.key, func() (int, error) {
+				return test.value, test.err
+			})
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if actual != test.wantActual || loaded != test.wantLoaded || err != test.wantErr {
+				t.Errorf("Unexpected result, wanted %d, %t, %v but got %d, %t, %v", test.wantActual, test.wantLoaded, test.wantErr, actual, loaded, err)
+			}
+		})
+	}
+}
+
+// TestLoadOrComputeSingleFlight checks that concurrent callers share one call
+// to the constructor, which runs without the Map locked.
+func TestLoadOrComputeSingleFlight(t *testing.T) {
+	m := NewMap[string, int]()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			actual, _, err := m.LoadOrCompute("one", func() (int, error) {
+				calls.Add(1)
+				<-release
+				return 1, nil
+			})
+			if actual != 1 || err != nil {
+				t.Errorf("Unexpected result, wanted 1, <nil> but got %d, %v", actual, err)
+			}
+		}()
+	}
+	for calls.Load() == 0 {
+		runtime.Gosched()
+	}
+	m.Store("two", 2)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Unexpected calls, wanted 1 but got %d", calls.Load())
+	}
+	checkContent(t, m, []string{"two", "one"}, map[string]int{"one": 1, "two": 2})
+}
+
+// TestLoadOrComputeShared checks that a caller waiting for an in-flight
+// constructor receives its value or error.
+func TestLoadOrComputeShared(t *testing.T) {
+	for name, test := range map[string]struct {
+		value      int
+		err        error
+		wantLoaded bool
+	}{
+		"value": {value: 1, wantLoaded: true},
+		"error": {err: errBoom},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap[string, int]()
+			c := &call[int]{done: make(chan struct{})}
+			m.calls = map[string]*call[int]{"one": c}
+
+			result := make(chan struct{})
+			go func() {
+				defer close(result)
+				actual, loaded, err := m.LoadOrCompute("one", func() (int, error) {
+					t.Error("The constructor should not have been called")
+					return 0, nil
+				})
+				if actual != test.value || loaded != test.wantLoaded || err != test.err {
+					t.Errorf("Unexpected result, wanted %d, %t, %v but got %d, %t, %v", test.value, test.wantLoaded, test.err, actual, loaded, err)
+				}
+			}()
+
+			c.value, c.err, c.returned = test.value, test.err, true
+			close(c.done)
+			<-result
+		})
+	}
+}
+
+func TestLoadOrComputePanic(t *testing.T) {
+	m := NewMap[string, int]()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("The constructor's panic should have been propagated")
+			}
+		}()
+		m.LoadOrCompute("one", func() (int, error) {
+			panic("boom")
+		})
+	}()
+	if len(m.calls) != 0 {
+		t.Errorf("Unexpected in-flight calls %v", m.calls)
+	}
+
+	actual, loaded, err := m.LoadOrCompute("one", func() (int, error) {
+		return 1, nil
+	})
+	if actual != 1 || loaded || err != nil {
+		t.Errorf("Unexpected result, wanted 1, false, <nil> but got %d, %t, %v", actual, loaded, err)
+	}
+}
+
+func TestLoadOrStore(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		value                    int
+		wantValue                int
+		wantLoaded               bool
+	}{
+		"nil_Store": {
+			wantOrder:  []string{"one"},
+			wantMap:    map[string]int{"one": 1},
+			key:        "one",
+			value:      1,
+			wantValue:  1,
+			wantLoaded: false,
+		},
+		"empty_Store": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			key:           "one",
+			value:         1,
+			wantValue:     1,
+			wantLoaded:    false,
+		},
+		"store_ten": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10},
+			key:           "ten",
+			value:         10,
+			wantValue:     10,
+			wantLoaded:    false,
+		},
+		"load_nine": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "nine",
+			value:         10,
+			wantValue:     9,
+			wantLoaded:    true,
+		},
+		"load_zero": {
+			startingOrder: []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			wantOrder:     []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9},
+			key:           "zero",
+			value:         10,
+			wantValue:     0,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			value, ok := m.LoadOrStore(test.key, test.value)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if ok != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			}
+		})
+	}
+}
+
+func TestMoveAfter(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		key, anchor              string
+		wantMoved                bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			key:           "one",
+			anchor:        "two",
+		},
+		"missing_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "four",
+			anchor:        "one",
+		},
+		"missing_anchor": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "one",
+			anchor:        "four",
+		},
+		"back": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			key:           "zero",
+			anchor:        "two",
+			wantMoved:     true,
+		},
+		"forward": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "three", "one", "two"},
+			key:           "three",
+			anchor:        "zero",
+			wantMoved:     true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three", "one"},
+			key:           "one",
+			anchor:        "three",
+			wantMoved:     true,
+		},
+		"nowhere": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "two",
+			anchor:        "one",
+			wantMoved:     true,
+		},
+		"same_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "one",
+			anchor:        "one",
+			wantMoved:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				moved := m.MoveAfter(test.key, test.anchor)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if moved != test.wantMoved {
+					t.Errorf("Unexpected moved, wanted %t but got %t", test.wantMoved, moved)
+				}
+			})
+		}
+	}
+}
+
+func TestMoveBefore(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		key, anchor              string
+		wantMoved                bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			key:           "one",
+			anchor:        "two",
+		},
+		"missing_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "four",
+			anchor:        "one",
+		},
+		"missing_anchor": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "one",
+			anchor:        "four",
+		},
+		"back": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			key:           "zero",
+			anchor:        "three",
+			wantMoved:     true,
+		},
+		"forward": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "three", "one", "two"},
+			key:           "three",
+			anchor:        "one",
+			wantMoved:     true,
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"two", "zero", "one", "three"},
+			key:           "two",
+			anchor:        "zero",
+			wantMoved:     true,
+		},
+		"nowhere": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "one",
+			anchor:        "two",
+			wantMoved:     true,
+		},
+		"same_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "one",
+			anchor:        "one",
+			wantMoved:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				moved := m.MoveBefore(test.key, test.anchor)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if moved != test.wantMoved {
+					t.Errorf("Unexpected moved, wanted %t but got %t", test.wantMoved, moved)
+				}
+			})
+		}
+	}
+}
+
+func TestMoveTo(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		key                      string
+		n                        int
+		wantMoved                bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			key:           "one",
+			n:             0,
+		},
+		"missing_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "four",
+			n:             0,
+		},
+		"back": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			key:           "zero",
+			n:             2,
+			wantMoved:     true,
+		},
+		"forward": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "three", "one", "two"},
+			key:           "three",
+			n:             1,
+			wantMoved:     true,
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			key:           "zero",
+			n:             -2,
+			wantMoved:     true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three", "one"},
+			key:           "one",
+			n:             -1,
+			wantMoved:     true,
+		},
+		"too_high": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three", "one"},
+			key:           "one",
+			n:             10,
+			wantMoved:     true,
+		},
+		"too_low": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"two", "zero", "one", "three"},
+			key:           "two",
+			n:             -10,
+			wantMoved:     true,
+		},
+		"nowhere": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "two",
+			n:             2,
+			wantMoved:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				moved := m.MoveTo(test.key, test.n)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if moved != test.wantMoved {
+					t.Errorf("Unexpected moved, wanted %t but got %t", test.wantMoved, moved)
+				}
+			})
+		}
+	}
+}
+
+func TestMoveToBack(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		key                      string
+		wantMoved                bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			key:           "one",
+		},
+		"missing_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "four",
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "three", "zero"},
+			key:           "zero",
+			wantMoved:     true,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "three", "one"},
+			key:           "one",
+			wantMoved:     true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "three",
+			wantMoved:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				moved := m.MoveToBack(test.key)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if moved != test.wantMoved {
+					t.Errorf("Unexpected moved, wanted %t but got %t", test.wantMoved, moved)
+				}
+			})
+		}
+	}
+}
+
+func TestMoveToFront(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		key                      string
+		wantMoved                bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			key:           "one",
+		},
+		"missing_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "four",
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			key:           "zero",
+			wantMoved:     true,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"two", "zero", "one", "three"},
+			key:           "two",
+			wantMoved:     true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "zero", "one", "two"},
+			key:           "three",
+			wantMoved:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				moved := m.MoveToFront(test.key)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if moved != test.wantMoved {
+					t.Errorf("Unexpected moved, wanted %t but got %t", test.wantMoved, moved)
+				}
+			})
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		key           string
+		wantKey       string
+		wantValue     int
+		wantOK        bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			key:           "one",
+		},
+		"missing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "four",
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "zero",
+			wantKey:       "one",
+			wantValue:     1,
+			wantOK:        true,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "two",
+			wantKey:       "three",
+			wantValue:     3,
+			wantOK:        true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				key, value, ok := m.Next(test.key)
+				if key != test.wantKey || value != test.wantValue || ok != test.wantOK {
+					t.Errorf("Unexpected result, wanted %q, %d, %t but got %q, %d, %t", test.wantKey, test.wantValue, test.wantOK, key, value, ok)
+				}
+			})
+		}
+	}
+}
+
+func TestPeekFirst(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		wantKey       string
+		wantValue     int
+		wantLoaded    bool
+	}{
+		"nil_peek": {},
+		"empty_peek": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+		},
+		"peek_one": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantKey:       "one",
+			wantValue:     1,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			key, value, ok := m.PeekFirst()
+			checkContent(t, &m.Map, test.startingOrder, test.startingMap)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
+			}
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if ok != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			}
+		})
+	}
+}
+
+func TestPeekLast(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		wantKey       string
+		wantValue     int
+		wantLoaded    bool
+	}{
+		"nil_peek": {},
+		"empty_peek": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+		},
+		"peek_three": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantKey:       "three",
+			wantValue:     3,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			key, value, ok := m.PeekLast()
+			checkContent(t, &m.Map, test.startingOrder, test.startingMap)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
+			}
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if ok != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, ok)
+			}
+		})
+	}
+}
+
+func TestPopN(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		n                        int
+		wantKeys                 []string
+		wantValues               []int
+	}{
+		"nil_pop": {
+			n:          2,
+			wantKeys:   []string{},
+			wantValues: []int{},
+		},
+		"empty_pop": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             2,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"pop_none": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "three"},
+			wantMap:       map[string]int{"one": 1, "two": 2, "three": 3},
+			n:             0,
+			wantKeys:      []string{},
+			wantValues:    []int{},
+		},
+		"pop_first_two": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three"},
+			wantMap:       map[string]int{"three": 3},
+			n:             2,
+			wantKeys:      []string{"one", "two"},
+			wantValues:    []int{1, 2},
+		},
+		"pop_last_two": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			n:             -2,
+			wantKeys:      []string{"three", "two"},
+			wantValues:    []int{3, 2},
+		},
+		"pop_too_many": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             10,
+			wantKeys:      []string{"one", "two", "three"},
+			wantValues:    []int{1, 2, 3},
+		},
+		"pop_too_many_backwards": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			n:             -10,
+			wantKeys:      []string{"three", "two", "one"},
+			wantValues:    []int{3, 2, 1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newSortMap(test.startingOrder, test.startingMap)
+			keys, values := m.PopN(test.n)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			if !reflect.DeepEqual(keys, test.wantKeys) {
+				t.Errorf("Unexpected keys\nactual: %#v\nwant  : %#v", keys, test.wantKeys)
+			}
+			if !reflect.DeepEqual(values, test.wantValues) {
+				t.Errorf("Unexpected values\nactual: %#v\nwant  : %#v", values, test.wantValues)
+			}
+		})
+	}
+}
+
+func TestPrev(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		key           string
+		wantKey       string
+		wantValue     int
+		wantOK        bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			key:           "one",
+		},
+		"missing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "four",
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "zero",
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "two",
+			wantKey:       "one",
+			wantValue:     1,
+			wantOK:        true,
+		},
+		"last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			key:           "three",
+			wantKey:       "two",
+			wantValue:     2,
+			wantOK:        true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				key, value, ok := m.Prev(test.key)
+				if key != test.wantKey || value != test.wantValue || ok != test.wantOK {
+					t.Errorf("Unexpected result, wanted %q, %d, %t but got %q, %d, %t", test.wantKey, test.wantValue, test.wantOK, key, value, ok)
+				}
+			})
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	type row struct {
+		key   string
+		value int
+	}
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		endOn         int
+		mutateOn      int
+		deleteOn      int
+		wantRows      []row
+	}{
+		"nil_range": {
+			wantRows: []row{},
+		},
+		"empty_range": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantRows:      []row{},
+		},
+		"range": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantRows:      []row{{"one", 1}, {"two", 2}, {"three", 3}},
+		},
+		"range_end_early": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			endOn:         1,
+			wantRows:      []row{{"one", 1}, {"two", 2}},
+		},
+		"range_but_mutate": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantRows:      []row{{"one", 1}, {"two", 2}, {"three", 0}},
+			mutateOn:      1,
+		},
+		"range_but_delete": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantRows:      []row{{"one", 1}, {"two", 2}},
+			deleteOn:      1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newSortMap(test.startingOrder, test.startingMap)
+			var calls int64
+			gotRows := make([]row, 0, len(test.wantRows))
+			s.Range(func(index int, key string, value int) bool {
+				atomic.AddInt64(&calls, 1)
+				if index > cap(gotRows) {
+					t.Errorf("Index %d over expected rows %d", index, cap(gotRows))
+				}
+
+				gotRows = append(gotRows, row{key, value})
+
+				if test.mutateOn > 0 && test.mutateOn == index {
+					s.Store("three", 0)
+				}
+
+				if test.deleteOn > 0 && test.deleteOn == index {
+					s.Delete("one")
+				}
+
+				if test.endOn > 0 && test.endOn == index {
+					return false
+				}
+				return true
+			})
+			if !reflect.DeepEqual(gotRows, test.wantRows) {
+				t.Errorf("Got unexpected rows\nactual: %#v\nwant  : %#v", gotRows, test.wantRows)
+			}
+		})
+	}
+}
+
+func TestRangeSnapshot(t *testing.T) {
+	type row struct {
+		key   string
+		value int
+	}
+	for name, test := range map[string]struct {
+		startingOrder []string
+		startingMap   map[string]int
+		endOn         int
+		mutateOn      int
+		wantRows      []row
+	}{
+		"nil_range": {
+			wantRows: []row{},
+		},
+		"empty_range": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantRows:      []row{},
+		},
+		"range": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantRows:      []row{{"one", 1}, {"two", 2}, {"three", 3}},
+		},
+		"range_end_early": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			endOn:         1,
+			wantRows:      []row{{"one", 1}, {"two", 2}},
+		},
+		"range_but_mutate": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantRows:      []row{{"one", 1}, {"two", 2}, {"three", 3}},
+			mutateOn:      1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newSortMap(test.startingOrder, test.startingMap)
+			gotRows := make([]row, 0, len(test.wantRows))
+			s.RangeSnapshot(func(index int, key string, value int) bool {
+				if index != len(gotRows) {
+					t.Errorf("Unexpected index, wanted %d but got %d", len(gotRows), index)
+				}
+
+				gotRows = append(gotRows, row{key, value})
+
+				if test.mutateOn > 0 && test.mutateOn == index {
+					s.Delete("one")
+					s.Store("three", 0)
+					s.StoreFirst("zero", 0)
+				}
+
+				if test.endOn > 0 && test.endOn == index {
+					return false
+				}
+				return true
+			})
+			if !reflect.DeepEqual(gotRows, test.wantRows) {
+				t.Errorf("Got unexpected rows\nactual: %#v\nwant  : %#v", gotRows, test.wantRows)
+			}
+		})
+	}
+}
+
+// TestRangeConcurrent is most useful with the race detector.
+func TestRangeConcurrent(t *testing.T) {
+	var (
+		m    Map[int, int]
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := (i*7 + w) % 150
+				switch i % 4 {
+				case 0:
+					m.Store(key, i)
+				case 1:
+					m.StoreFirst(key, i)
+				case 2:
+					m.Delete(key)
+				case 3:
+					m.Swap(i%50, key%50)
+				}
+			}
+		}(w)
+	}
+
+	for i := 0; i < 100; i++ {
+		m.Range(func(index int, key, value int) bool {
+			if key < 0 || key >= 150 {
+				t.Errorf("Unexpected key %d at index %d", key, index)
+			}
+			return true
+		})
+		seen := make(map[int]bool)
+		want := 0
+		m.RangeSnapshot(func(index int, key, value int) bool {
+			if index != want {
+				t.Errorf("Unexpected index, wanted %d but got %d", want, index)
+			}
+			want++
+			if seen[key] {
+				t.Errorf("Key %d seen twice in snapshot", key)
+			}
+			seen[key] = true
+			return true
+		})
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestReverse(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+		},
+		"one": {
+			startingOrder: []string{"zero"},
+			startingMap:   map[string]int{"zero": 0},
+			wantOrder:     []string{"zero"},
+		},
+		"even": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "two", "one", "zero"},
+		},
+		"odd": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"two", "one", "zero"},
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				m.Reverse()
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+			})
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		n                        int
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			n:             1,
+		},
+		"zero": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			n:             0,
+		},
+		"one": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "three", "zero"},
+			n:             1,
+		},
+		"three": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "zero", "one", "two"},
+			n:             3,
+		},
+		"len": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			n:             4,
+		},
+		"more_than_len": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"two", "three", "zero", "one"},
+			n:             6,
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "zero", "one", "two"},
+			n:             -1,
+		},
+		"negative_more_than_len": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"two", "three", "zero", "one"},
+			n:             -6,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				m.Rotate(test.n)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+			})
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		i, j                     int
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             0,
+			j:             2,
+		},
+		"all": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			i:             0,
+			j:             4,
+		},
+		"first": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero"},
+			wantMap:       map[string]int{"zero": 0},
+			i:             0,
+			j:             1,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two"},
+			wantMap:       map[string]int{"one": 1, "two": 2},
+			i:             1,
+			j:             3,
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two"},
+			wantMap:       map[string]int{"one": 1, "two": 2},
+			i:             -3,
+			j:             -1,
+		},
+		"last_two": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"two", "three"},
+			wantMap:       map[string]int{"two": 2, "three": 3},
+			i:             -2,
+			j:             4,
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             2,
+			j:             2,
+		},
+		"backwards": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             3,
+			j:             1,
 		},
-		"range_end_early": {
-			startingOrder: []string{"one", "two", "three"},
-			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
-			endOn:         1,
-			wantRows:      []row{{"one", 1}, {"two", 2}},
+		"too_wide": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			i:             -10,
+			j:             10,
 		},
-		"range_but_mutate": {
-			startingOrder: []string{"one", "two", "three"},
-			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
-			wantRows:      []row{{"one", 1}, {"two", 2}, {"three", 0}},
-			mutateOn:      1,
+		"out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			i:             4,
+			j:             10,
 		},
 	} {
-		t.Run(name, func(t *testing.T) {
-			s := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
-			var calls int64
-			gotRows := make([]row, 0, len(test.wantRows))
-			s.Range(func(index int, key string, value int) bool {
-				atomic.AddInt64(&calls, 1)
-				if index > cap(gotRows) {
-					t.Errorf("Index %d over expected rows %d", index, cap(gotRows))
-				}
-
-				gotRows = append(gotRows, row{key, value})
-
-				if test.mutateOn > 0 && test.mutateOn == index {
-					s.order = []string{}
-					s.dirty = map[string]int{}
-				}
-
-				if test.endOn > 0 && test.endOn == index {
-					return false
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				s := m.Slice(test.i, test.j)
+				checkContent(t, &s.Map, test.wantOrder, test.wantMap)
+				checkContent(t, &m.Map, test.startingOrder, test.startingMap)
+				if s.local.opts.backing != b {
+					t.Errorf("Unexpected backing, wanted %d but got %d", b, s.local.opts.backing)
 				}
-				return true
 			})
-			if !reflect.DeepEqual(gotRows, test.wantRows) {
-				t.Errorf("Got unexpected rows\nactual: %#v\nwant  : %#v", gotRows, test.wantRows)
-			}
-		})
+		}
 	}
 }
 
@@ -708,7 +3255,7 @@ func TestSort(t *testing.T) {
 	s := SortMap[float64, string]{}
 	for i := 0; i < 1000; i++ {
 		n := rand.Float64()
-		s.store(rand.Float64(), strconv.FormatFloat(n, 'E', -1, 64))
+		s.local.Store(rand.Float64(), strconv.FormatFloat(n, 'E', -1, 64))
 	}
 	if sort.IsSorted(&s) {
 		t.Error("you're extrodinarily unlucky")
@@ -759,20 +3306,380 @@ func TestStore(t *testing.T) {
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
+			m := newSortMap(test.startingOrder, test.startingMap)
 			m.Store(test.key, test.value)
-			if !reflect.DeepEqual(m.dirty, test.wantMap) {
-				t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", m.dirty, test.wantMap)
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+		})
+	}
+}
+
+func TestStoreAfter(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		anchor, key              string
+		value                    int
+		wantFound                bool
+	}{
+		"empty_missing_anchor": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			anchor:        "one",
+			key:           "two",
+			value:         2,
+		},
+		"missing_anchor": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "four",
+			key:           "five",
+			value:         5,
+		},
+		"insert": {
+			startingOrder: []string{"zero", "one", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "one",
+			key:           "two",
+			value:         2,
+			wantFound:     true,
+		},
+		"insert_last": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "two",
+			key:           "three",
+			value:         3,
+			wantFound:     true,
+		},
+		"move_back": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			wantMap:       map[string]int{"zero": 10, "one": 1, "two": 2, "three": 3},
+			anchor:        "two",
+			key:           "zero",
+			value:         10,
+			wantFound:     true,
+		},
+		"move_forward": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "three", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "zero",
+			key:           "three",
+			value:         3,
+			wantFound:     true,
+		},
+		"move_nowhere": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "one",
+			key:           "two",
+			value:         2,
+			wantFound:     true,
+		},
+		"same_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+			anchor:        "one",
+			key:           "one",
+			value:         10,
+			wantFound:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				found := m.StoreAfter(test.anchor, test.key, test.value)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if found != test.wantFound {
+					t.Errorf("Unexpected found, wanted %t but got %t", test.wantFound, found)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreAll(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		pairs                    []Pair[string, int]
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			pairs:         []Pair[string, int]{{"zero", 0}, {"one", 1}},
+			wantOrder:     []string{"zero", "one"},
+			wantMap:       map[string]int{"zero": 0, "one": 1},
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			pairs:         []Pair[string, int]{},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+		},
+		"new": {
+			startingOrder: []string{"zero", "one"},
+			startingMap:   map[string]int{"zero": 0, "one": 1},
+			pairs:         []Pair[string, int]{{"two", 2}, {"three", 3}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+		},
+		"existing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			pairs:         []Pair[string, int]{{"one", 10}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+		},
+		"mixed": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			pairs:         []Pair[string, int]{{"three", 3}, {"one", 10}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+		},
+		"duplicate": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			pairs:         []Pair[string, int]{{"three", 0}, {"three", 3}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				m.StoreAll(Pairs(test.pairs))
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			})
+		}
+	}
+}
+
+func TestStoreAllFirst(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		pairs                    []Pair[string, int]
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			pairs:         []Pair[string, int]{{"zero", 0}, {"one", 1}},
+			wantOrder:     []string{"zero", "one"},
+			wantMap:       map[string]int{"zero": 0, "one": 1},
+		},
+		"none": {
+			startingOrder: []string{"zero", "one", "two"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2},
+			pairs:         []Pair[string, int]{},
+			wantOrder:     []string{"zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2},
+		},
+		"new": {
+			startingOrder: []string{"two", "three"},
+			startingMap:   map[string]int{"two": 2, "three": 3},
+			pairs:         []Pair[string, int]{{"zero", 0}, {"one", 1}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+		},
+		"existing": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			pairs:         []Pair[string, int]{{"one", 10}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+		},
+		"mixed": {
+			startingOrder: []string{"one", "three"},
+			startingMap:   map[string]int{"one": 1, "three": 3},
+			pairs:         []Pair[string, int]{{"zero", 0}, {"one", 10}, {"two", 2}},
+			wantOrder:     []string{"zero", "two", "one", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+		},
+		"duplicate": {
+			startingOrder: []string{"two", "three"},
+			startingMap:   map[string]int{"two": 2, "three": 3},
+			pairs:         []Pair[string, int]{{"zero", 0}, {"one", 1}, {"zero", 0}},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				m.StoreAllFirst(Pairs(test.pairs))
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+			})
+		}
+	}
+}
+
+func TestStoreAllNil(t *testing.T) {
+	m := NewMap[string, int]()
+	m.Store("zero", 0)
+	m.StoreAll(nil)
+	m.StoreAllFirst(nil)
+	checkContent(t, m, []string{"zero"}, map[string]int{"zero": 0})
+
+	var l LocalMap[string, int]
+	l.StoreAll(nil)
+	l.StoreAllFirst(nil)
+	checkLocalContent(t, &l, nil, nil)
+}
+
+// TestStoreAllAtomic checks that other goroutines never see part of a batch.
+func TestStoreAllAtomic(t *testing.T) {
+	m := NewMap[int, int]()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m.Len() < 2000 {
+			if n := m.Len(); n%1000 != 0 {
+				t.Errorf("Unexpected length %d", n)
+				return
 			}
-			if !reflect.DeepEqual(m.order, test.wantOrder) {
-				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", m.order, test.wantOrder)
+		}
+	}()
+
+	batch := func(from int) iter.Seq2[int, int] {
+		return func(yield func(int, int) bool) {
+			for i := from; i < from+1000; i++ {
+				if !yield(i, i) {
+					return
+				}
 			}
-		})
+		}
+	}
+	m.StoreAll(batch(0))
+	m.StoreAllFirst(batch(1000))
+	<-done
+
+	if key, _, _ := m.Index(0); key != 1000 {
+		t.Errorf("Unexpected first key, wanted 1000 but got %d", key)
+	}
+	if key, _, _ := m.Index(1000); key != 0 {
+		t.Errorf("Unexpected key at index 1000, wanted 0 but got %d", key)
+	}
+}
+
+func TestStoreBefore(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		anchor, key              string
+		value                    int
+		wantFound                bool
+	}{
+		"empty_missing_anchor": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			wantMap:       map[string]int{},
+			anchor:        "one",
+			key:           "two",
+			value:         2,
+		},
+		"missing_anchor": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "four",
+			key:           "five",
+			value:         5,
+		},
+		"insert": {
+			startingOrder: []string{"zero", "one", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "three",
+			key:           "two",
+			value:         2,
+			wantFound:     true,
+		},
+		"insert_first": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "one",
+			key:           "zero",
+			value:         0,
+			wantFound:     true,
+		},
+		"move_back": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "two", "zero", "three"},
+			wantMap:       map[string]int{"zero": 10, "one": 1, "two": 2, "three": 3},
+			anchor:        "three",
+			key:           "zero",
+			value:         10,
+			wantFound:     true,
+		},
+		"move_forward": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "zero", "one", "two"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "zero",
+			key:           "three",
+			value:         3,
+			wantFound:     true,
+		},
+		"move_nowhere": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			anchor:        "two",
+			key:           "one",
+			value:         1,
+			wantFound:     true,
+		},
+		"same_key": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			wantMap:       map[string]int{"zero": 0, "one": 10, "two": 2, "three": 3},
+			anchor:        "one",
+			key:           "one",
+			value:         10,
+			wantFound:     true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				found := m.StoreBefore(test.anchor, test.key, test.value)
+				checkContent(t, &m.Map, test.wantOrder, test.wantMap)
+				if found != test.wantFound {
+					t.Errorf("Unexpected found, wanted %t but got %t", test.wantFound, found)
+				}
+			})
+		}
 	}
 }
 
@@ -814,19 +3721,9 @@ func TestStoreFirst(t *testing.T) {
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
+			m := newSortMap(test.startingOrder, test.startingMap)
 			m.StoreFirst(test.key, test.value)
-			if !reflect.DeepEqual(m.dirty, test.wantMap) {
-				t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", m.dirty, test.wantMap)
-			}
-			if !reflect.DeepEqual(m.order, test.wantOrder) {
-				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", m.order, test.wantOrder)
-			}
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
 		})
 	}
 }
@@ -837,20 +3734,12 @@ func TestString(t *testing.T) {
 		want   string
 	}{
 		"Map": {
-			want: "github.com/brackendawson/ordered.Map[string,int][seven:7 nine:9 one:1]",
-			object: &Map[string, int]{
-				order: []string{"seven", "nine", "one"},
-				dirty: map[string]int{"one": 1, "seven": 7, "nine": 9},
-			},
+			want:   "github.com/brackendawson/ordered.Map[string,int][seven:7 nine:9 one:1]",
+			object: &newSortMap([]string{"seven", "nine", "one"}, map[string]int{"one": 1, "seven": 7, "nine": 9}).Map,
 		},
 		"SortMap": {
-			want: "github.com/brackendawson/ordered.SortMap[float64,bool][6.7:true 1:false -0.2:true]",
-			object: &SortMap[float64, bool]{
-				Map: Map[float64, bool]{
-					order: []float64{6.7, 1, -0.2},
-					dirty: map[float64]bool{6.7: true, 1: false, -0.2: true},
-				},
-			},
+			want:   "github.com/brackendawson/ordered.SortMap[float64,bool][6.7:true 1:false -0.2:true]",
+			object: newSortMap([]float64{6.7, 1, -0.2}, map[float64]bool{6.7: true, 1: false, -0.2: true}),
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -978,69 +3867,268 @@ func TestSwap(t *testing.T) {
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			m := SortMap[string, int]{
-				Map[string, int]{
-					order: test.startingOrder,
-					dirty: test.startingMap,
-				},
-			}
+			m := newSortMap(test.startingOrder, test.startingMap)
 			m.Swap(test.i, test.j)
-			if !reflect.DeepEqual(m.dirty, test.wantMap) {
-				t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", m.dirty, test.wantMap)
-			}
-			if !reflect.DeepEqual(m.order, test.wantOrder) {
-				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", m.order, test.wantOrder)
-			}
+			checkContent(t, &m.Map, test.wantOrder, test.wantMap)
 		})
 	}
 }
 
-// newLoadAndDelete is not an improvement
-func (m *Map[K, V]) newLoadAndDelete(key K) (value V, loaded bool) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func TestSwapAt(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		i, j                     int
+		wantSwapped              bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			i:             0,
+			j:             0,
+		},
+		"i_out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			i:             4,
+			j:             0,
+		},
+		"j_out_of_range": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			i:             0,
+			j:             -5,
+		},
+		"first_last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "one", "two", "zero"},
+			i:             0,
+			j:             3,
+			wantSwapped:   true,
+		},
+		"negative": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "one", "three"},
+			i:             -3,
+			j:             -2,
+			wantSwapped:   true,
+		},
+		"same": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			i:             1,
+			j:             -3,
+			wantSwapped:   true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				swapped := m.SwapAt(test.i, test.j)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if swapped != test.wantSwapped {
+					t.Errorf("Unexpected swapped, wanted %t but got %t", test.wantSwapped, swapped)
+				}
+			})
+		}
+	}
+}
+
+func TestSwapKeys(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap              map[string]int
+		a, b                     string
+		wantSwapped              bool
+	}{
+		"empty": {
+			startingOrder: []string{},
+			startingMap:   map[string]int{},
+			wantOrder:     []string{},
+			a:             "one",
+			b:             "two",
+		},
+		"missing_a": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			a:             "four",
+			b:             "one",
+		},
+		"missing_b": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			a:             "one",
+			b:             "four",
+		},
+		"first_last": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"three", "one", "two", "zero"},
+			a:             "zero",
+			b:             "three",
+			wantSwapped:   true,
+		},
+		"middle": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "two", "one", "three"},
+			a:             "two",
+			b:             "one",
+			wantSwapped:   true,
+		},
+		"same": {
+			startingOrder: []string{"zero", "one", "two", "three"},
+			startingMap:   map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"zero", "one", "two", "three"},
+			a:             "one",
+			b:             "one",
+			wantSwapped:   true,
+		},
+	} {
+		for backingName, b := range backings {
+			t.Run(name+"/"+backingName, func(t *testing.T) {
+				m := NewSortMap[string, int](WithBacking(b))
+				fill(&m.local, test.startingOrder, test.startingMap)
+				swapped := m.SwapKeys(test.a, test.b)
+				checkContent(t, &m.Map, test.wantOrder, test.startingMap)
+				if swapped != test.wantSwapped {
+					t.Errorf("Unexpected swapped, wanted %t but got %t", test.wantSwapped, swapped)
+				}
+			})
+		}
+	}
+}
 
-	for i := 0; i < len(m.order); i++ {
-		if m.order[i] != key {
+// sliceLoadAndDelete is the linear scan LoadAndDelete used when Map kept its
+// order in a slice, it is kept as a baseline for BenchmarkLoadAndDelete.
+func sliceLoadAndDelete[K comparable, V any](order []K, dirty map[K]V, key K) ([]K, V, bool) {
+	for i := 0; i < len(order); i++ {
+		if order[i] != key {
 			continue
 		}
 
-		end := m.order[i+1:]
-		m.order = m.order[:len(m.order)-1]
-		copy(m.order[i:], end)
+		order = append(order[:i], order[i+1:]...)
 		break
 	}
 
-	value, loaded = m.dirty[key]
-	delete(m.dirty, key)
-	return
+	value, loaded := dirty[key]
+	delete(dirty, key)
+	return order, value, loaded
 }
 
 func BenchmarkLoadAndDelete(b *testing.B) {
-	root := Map[int, string]{}
-	for i := 0; i < 1000; i++ {
-		root.Store(i, strconv.Itoa(i))
-	}
-	b.Run("old", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			// b.StopTimer()
-			m := Map[int, string]{}
-			copy(m.order, root.order)
-			// b.StartTimer()
-			for i := 400; i < 600; i++ {
-				m.LoadAndDelete(i)
+	for _, size := range []int{1000, 10000} {
+		b.Run("slice/"+strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				order := make([]int, size)
+				dirty := make(map[int]string, size)
+				for i := range order {
+					order[i] = i
+					dirty[i] = strconv.Itoa(i)
+				}
+				b.StartTimer()
+				for i := size * 2 / 5; i < size*3/5; i++ {
+					order, _, _ = sliceLoadAndDelete(order, dirty, i)
+				}
 			}
-		}
-	})
-	b.Run("new", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			// b.StopTimer()
-			m := Map[int, string]{}
-			copy(m.order, root.order)
-			// b.StartTimer()
-			for i := 400; i < 600; i++ {
-				m.newLoadAndDelete(i)
+		})
+		b.Run("default/"+strconv.Itoa(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				m := &Map[int, string]{}
+				for i := 0; i < size; i++ {
+					m.Store(i, strconv.Itoa(i))
+				}
+				b.StartTimer()
+				for i := size * 2 / 5; i < size*3/5; i++ {
+					m.LoadAndDelete(i)
+				}
 			}
+		})
+		for name, backing := range backings {
+			b.Run(name+"/"+strconv.Itoa(size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					m := NewMap[int, string](WithBacking(backing))
+					for i := 0; i < size; i++ {
+						m.Store(i, strconv.Itoa(i))
+					}
+					b.StartTimer()
+					for i := size * 2 / 5; i < size*3/5; i++ {
+						m.LoadAndDelete(i)
+					}
+				}
+			})
 		}
+	}
+}
+
+func newSortMap[K Ordered, V any](order []K, values map[K]V) *SortMap[K, V] {
+	m := &SortMap[K, V]{}
+	fill(&m.local, order, values)
+	return m
+}
+
+// fill stores the keys in order with their values in l. l is initialised if
+// values is not nil.
+func fill[K comparable, V any](l *LocalMap[K, V], order []K, values map[K]V) {
+	if values != nil {
+		l.lazyInit()
+	}
+	for _, key := range order {
+		l.Store(key, values[key])
+	}
+}
+
+// content returns the order and values of l, they are nil if l has never been
+// written to.
+func content[K comparable, V any](l *LocalMap[K, V]) (order []K, values map[K]V) {
+	if l.dirty == nil {
+		return
+	}
+
+	order = []K{}
+	values = map[K]V{}
+	l.each(func(e *entry[K, V]) {
+		order = append(order, e.key)
+		values[e.key] = e.value
 	})
+	return
+}
+
+func checkContent[K comparable, V any](t *testing.T, m *Map[K, V], wantOrder []K, wantMap map[K]V) {
+	t.Helper()
+	checkLocalContent(t, &m.local, wantOrder, wantMap)
+}
+
+func checkLocalContent[K comparable, V any](t *testing.T, l *LocalMap[K, V], wantOrder []K, wantMap map[K]V) {
+	t.Helper()
+	order, values := content(l)
+	if !reflect.DeepEqual(values, wantMap) {
+		t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", values, wantMap)
+	}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", order, wantOrder)
+	}
+	if len(order) != l.len() {
+		t.Errorf("Unexpected length, order has %d keys but len is %d", len(order), l.len())
+	}
+	if len(l.dirty) != len(order) {
+		t.Errorf("Unexpected dirty length, order has %d keys but dirty has %d", len(order), len(l.dirty))
+	}
+	for i, key := range order {
+		if e := l.dirty[key]; e != l.order.at(i) {
+			t.Errorf("Unexpected dirty entry for key %#v at index %d", key, i)
+		}
+	}
 }
@@ -0,0 +1,194 @@
+package ordered
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ReadMostlyMap is an ordered map data structure that is safe for concurrent
+// use by multiple goroutines without additional locking or coordination. It is
+// optimised for maps which are read far more often than they are written.
+//
+// Reads take no lock, each read method sees a consistent immutable snapshot of
+// the map. Writes are serialised, each write copies the snapshot, modifies the
+// copy and publishes it, so writes are O(n).
+//
+// The zero ReadMostlyMap is empty and ready for use. A ReadMostlyMap must not
+// be copied after first use.
+type ReadMostlyMap[K comparable, V any] struct {
+	snap atomic.Pointer[snapshot[K, V]]
+	mu   sync.Mutex
+}
+
+// snapshot is an immutable copy of the contents of a ReadMostlyMap. The key at
+// index i is keys[i] and its value is values[i], index maps each key to i.
+type snapshot[K comparable, V any] struct {
+	keys   []K
+	values []V
+	index  map[K]int
+}
+
+// load returns the current snapshot.
+func (m *ReadMostlyMap[K, V]) load() *snapshot[K, V] {
+	if s := m.snap.Load(); s != nil {
+		return s
+	}
+	return &snapshot[K, V]{}
+}
+
+// Delete deletes the value for a key.
+func (m *ReadMostlyMap[K, V]) Delete(key K) {
+	m.LoadAndDelete(key)
+}
+
+// Index loads the key and value of the key at index n. The loaded result
+// reports whether the index was in range. Negative value of n index from the
+// end of the ReadMostlyMap.
+func (m *ReadMostlyMap[K, V]) Index(n int) (key K, value V, loaded bool) {
+	s := m.load()
+	if n < 0 {
+		n += len(s.keys)
+	}
+	if n < 0 || n >= len(s.keys) {
+		return
+	}
+	return s.keys[n], s.values[n], true
+}
+
+// Len returns the number of keys in the ReadMostlyMap.
+func (m *ReadMostlyMap[K, V]) Len() int {
+	return len(m.load().keys)
+}
+
+// Load returns the value stored in the map for a key, or nil if no value is
+// present. The ok result indicates whether value was found in the map.
+func (m *ReadMostlyMap[K, V]) Load(key K) (value V, ok bool) {
+	s := m.load()
+	i, ok := s.index[key]
+	if !ok {
+		return
+	}
+	return s.values[i], true
+}
+
+// LoadAndDelete deletes the value for a key, returning the previous value if
+// any. The loaded result reports whether the key was present.
+func (m *ReadMostlyMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.load()
+	i, loaded := s.index[key]
+	if !loaded {
+		return
+	}
+
+	value = s.values[i]
+	m.publish(slices.Delete(slices.Clone(s.keys), i, i+1), slices.Delete(slices.Clone(s.values), i, i+1))
+	return value, true
+}
+
+// LoadOrStore returns the existing value for the key if present. Otherwise, it
+// stores and returns the given value, adding it to the end. The loaded result
+// is true if the value was loaded, false if stored.
+func (m *ReadMostlyMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	if actual, loaded = m.Load(key); loaded {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.load()
+	if i, ok := s.index[key]; ok {
+		return s.values[i], true
+	}
+	m.storeAt(s, len(s.keys), key, value)
+	return value, false
+}
+
+// Range calls f sequentially for each key and value present in the map at the
+// moment Range was called. If f returns false, range stops the iteration.
+//
+// Range always iterates over a consistent snapshot of the ReadMostlyMap's
+// contents, it does not block other methods on the receiver; even f itself may
+// call any method on m.
+func (m *ReadMostlyMap[K, V]) Range(f func(index int, key K, value V) bool) {
+	s := m.load()
+	for index := range s.keys {
+		if !f(index, s.keys[index], s.values[index]) {
+			return
+		}
+	}
+}
+
+// Store sets the value for a key adding it to the end if it was not in the map.
+func (m *ReadMostlyMap[K, V]) Store(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.load()
+	m.storeAt(s, len(s.keys), key, value)
+}
+
+// StoreFirst sets the value for a key adding it to the beginning if it was not
+// in the map.
+func (m *ReadMostlyMap[K, V]) StoreFirst(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.storeAt(m.load(), 0, key, value)
+}
+
+// storeAt publishes a copy of s with the value for a key set, adding it at
+// index n if it was not in the map. Only the values are copied if the key was
+// present.
+func (m *ReadMostlyMap[K, V]) storeAt(s *snapshot[K, V], n int, key K, value V) {
+	if i, ok := s.index[key]; ok {
+		values := slices.Clone(s.values)
+		values[i] = value
+		m.snap.Store(&snapshot[K, V]{keys: s.keys, values: values, index: s.index})
+		return
+	}
+
+	m.publish(slices.Insert(slices.Clone(s.keys), n, key), slices.Insert(slices.Clone(s.values), n, value))
+}
+
+// publish indexes keys and stores them with values as the current snapshot.
+func (m *ReadMostlyMap[K, V]) publish(keys []K, values []V) {
+	index := make(map[K]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+	m.snap.Store(&snapshot[K, V]{keys: keys, values: values, index: index})
+}
+
+// String formats the map for printing
+func (m *ReadMostlyMap[K, V]) String() string {
+	s := m.load()
+	str := typeName(m) + "["
+	var space string
+	for i := range s.keys {
+		str += space + fmt.Sprint(s.keys[i]) + ":" + fmt.Sprint(s.values[i])
+		space = " "
+	}
+	return str + "]"
+}
+
+// Swap swaps the position of the keys at indicies i and j.
+func (m *ReadMostlyMap[K, V]) Swap(i, j int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.load()
+	if i < 0 || i >= len(s.keys) || j < 0 || j >= len(s.keys) {
+		return
+	}
+
+	keys, values := slices.Clone(s.keys), slices.Clone(s.values)
+	keys[i], keys[j] = keys[j], keys[i]
+	values[i], values[j] = values[j], values[i]
+	m.publish(keys, values)
+}
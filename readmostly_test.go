@@ -0,0 +1,282 @@
+package ordered
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func newReadMostlyMap[K comparable, V any](order []K, values map[K]V) *ReadMostlyMap[K, V] {
+	m := &ReadMostlyMap[K, V]{}
+	for _, key := range order {
+		m.Store(key, values[key])
+	}
+	return m
+}
+
+func checkReadMostlyContent[K comparable, V any](t *testing.T, m *ReadMostlyMap[K, V], wantOrder []K, wantMap map[K]V) {
+	t.Helper()
+	order := []K{}
+	values := map[K]V{}
+	m.Range(func(index int, key K, value V) bool {
+		order = append(order, key)
+		values[key] = value
+		return true
+	})
+	if !reflect.DeepEqual(values, wantMap) {
+		t.Errorf("Unexpected map content\nactual: %#v\nwant  : %#v", values, wantMap)
+	}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", order, wantOrder)
+	}
+	s := m.load()
+	for i, key := range s.keys {
+		if s.index[key] != i {
+			t.Errorf("Key %v indexed at %d but is at %d", key, s.index[key], i)
+		}
+	}
+	if len(s.index) != len(s.keys) || len(s.values) != len(s.keys) {
+		t.Errorf("Snapshot has %d keys, %d values and %d indexed", len(s.keys), len(s.values), len(s.index))
+	}
+}
+
+func TestReadMostlyMapLoadAndDelete(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		key                      string
+		wantValue                int
+		wantLoaded               bool
+	}{
+		"empty_loadAndDelete": {
+			wantOrder: []string{},
+			wantMap:   map[string]int{},
+			key:       "notakey",
+		},
+		"loadAndDelete_none": {
+			startingOrder: []string{"one"},
+			startingMap:   map[string]int{"one": 1},
+			wantOrder:     []string{"one"},
+			wantMap:       map[string]int{"one": 1},
+			key:           "notakey",
+		},
+		"loadAndDelete_two": {
+			startingOrder: []string{"one", "two", "three"},
+			startingMap:   map[string]int{"one": 1, "two": 2, "three": 3},
+			wantOrder:     []string{"one", "three"},
+			wantMap:       map[string]int{"one": 1, "three": 3},
+			key:           "two",
+			wantValue:     2,
+			wantLoaded:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newReadMostlyMap(test.startingOrder, test.startingMap)
+			value, loaded := m.LoadAndDelete(test.key)
+			checkReadMostlyContent(t, m, test.wantOrder, test.wantMap)
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if loaded != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
+			}
+		})
+	}
+}
+
+func TestReadMostlyMapStore(t *testing.T) {
+	for name, test := range map[string]struct {
+		startingOrder, wantOrder []string
+		startingMap, wantMap     map[string]int
+		first                    bool
+		key                      string
+		value                    int
+	}{
+		"empty_insert": {
+			wantOrder: []string{"one"},
+			wantMap:   map[string]int{"one": 1},
+			key:       "one",
+			value:     1,
+		},
+		"nonempty_insert": {
+			startingOrder: []string{"one"},
+			startingMap:   map[string]int{"one": 1},
+			wantOrder:     []string{"one", "two"},
+			wantMap:       map[string]int{"one": 1, "two": 2},
+			key:           "two",
+			value:         2,
+		},
+		"nonempty_insert_first": {
+			startingOrder: []string{"one"},
+			startingMap:   map[string]int{"one": 1},
+			wantOrder:     []string{"two", "one"},
+			wantMap:       map[string]int{"one": 1, "two": 2},
+			first:         true,
+			key:           "two",
+			value:         2,
+		},
+		"duplicate_insert": {
+			startingOrder: []string{"one", "two"},
+			startingMap:   map[string]int{"one": 1, "two": 2},
+			wantOrder:     []string{"one", "two"},
+			wantMap:       map[string]int{"one": 11, "two": 2},
+			key:           "one",
+			value:         11,
+		},
+		"duplicate_insert_first": {
+			startingOrder: []string{"one", "two"},
+			startingMap:   map[string]int{"one": 1, "two": 2},
+			wantOrder:     []string{"one", "two"},
+			wantMap:       map[string]int{"one": 1, "two": 22},
+			first:         true,
+			key:           "two",
+			value:         22,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newReadMostlyMap(test.startingOrder, test.startingMap)
+			before := m.load()
+			if test.first {
+				m.StoreFirst(test.key, test.value)
+			} else {
+				m.Store(test.key, test.value)
+			}
+			checkReadMostlyContent(t, m, test.wantOrder, test.wantMap)
+			for i, key := range before.keys {
+				if before.values[i] != test.startingMap[key] {
+					t.Errorf("Store modified the previous snapshot of key %q", key)
+				}
+			}
+		})
+	}
+}
+
+func TestReadMostlyMapIndex(t *testing.T) {
+	m := newReadMostlyMap([]string{"zero", "one", "two"}, map[string]int{"zero": 0, "one": 1, "two": 2})
+	for name, test := range map[string]struct {
+		index      int
+		wantKey    string
+		wantValue  int
+		wantLoaded bool
+	}{
+		"index_zero":                   {index: 0, wantKey: "zero", wantValue: 0, wantLoaded: true},
+		"index_two":                    {index: 2, wantKey: "two", wantValue: 2, wantLoaded: true},
+		"index_last":                   {index: -1, wantKey: "two", wantValue: 2, wantLoaded: true},
+		"index_out_of_range":           {index: 3},
+		"index_out_of_range_backwards": {index: -4},
+	} {
+		t.Run(name, func(t *testing.T) {
+			key, value, loaded := m.Index(test.index)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
+			}
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if loaded != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
+			}
+		})
+	}
+}
+
+func TestReadMostlyMapSwap(t *testing.T) {
+	for name, test := range map[string]struct {
+		i, j      int
+		wantOrder []string
+	}{
+		"zero_two":  {i: 0, j: 2, wantOrder: []string{"two", "one", "zero"}},
+		"one_one":   {i: 1, j: 1, wantOrder: []string{"zero", "one", "two"}},
+		"one_three": {i: 1, j: 3, wantOrder: []string{"zero", "one", "two"}},
+		"minus_one": {i: -1, j: 1, wantOrder: []string{"zero", "one", "two"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newReadMostlyMap([]string{"zero", "one", "two"}, map[string]int{"zero": 0, "one": 1, "two": 2})
+			m.Swap(test.i, test.j)
+			checkReadMostlyContent(t, m, test.wantOrder, map[string]int{"zero": 0, "one": 1, "two": 2})
+		})
+	}
+}
+
+func TestReadMostlyMapLoadOrStore(t *testing.T) {
+	var m ReadMostlyMap[string, int]
+	if actual, loaded := m.LoadOrStore("one", 1); actual != 1 || loaded {
+		t.Errorf("Unexpected LoadOrStore, wanted 1 false but got %d %t", actual, loaded)
+	}
+	if actual, loaded := m.LoadOrStore("one", 2); actual != 1 || !loaded {
+		t.Errorf("Unexpected LoadOrStore, wanted 1 true but got %d %t", actual, loaded)
+	}
+	if value, ok := m.Load("one"); value != 1 || !ok {
+		t.Errorf("Unexpected Load, wanted 1 true but got %d %t", value, ok)
+	}
+	if m.Len() != 1 {
+		t.Errorf("Unexpected length, wanted 1 but got %d", m.Len())
+	}
+	want := "github.com/brackendawson/ordered.ReadMostlyMap[string,int][one:1]"
+	if got := m.String(); got != want {
+		t.Errorf("Not equal:\n\twant: %s\n\tgot : %s", want, got)
+	}
+}
+
+// TestReadMostlyMapConcurrent is most useful with the race detector. Writers
+// store keys in pairs so every consistent snapshot has an even length.
+func TestReadMostlyMapConcurrent(t *testing.T) {
+	var (
+		m    ReadMostlyMap[int, int]
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i += 2 {
+			m.mu.Lock()
+			s := m.load()
+			m.publish(append(append([]int{}, s.keys...), i, i+1), append(append([]int{}, s.values...), i, i+1))
+			m.mu.Unlock()
+		}
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+		}
+		var n int
+		m.Range(func(index int, key, value int) bool {
+			n++
+			return true
+		})
+		if n%2 != 0 {
+			t.Fatalf("Range saw an inconsistent snapshot of %d keys", n)
+		}
+		m.Load(n)
+		m.Index(n / 2)
+	}
+}
+
+func BenchmarkReadMostly(b *testing.B) {
+	type loader interface {
+		Load(int) (string, bool)
+		Store(int, string)
+	}
+	for name, m := range map[string]loader{
+		"Map":           &Map[int, string]{},
+		"ReadMostlyMap": &ReadMostlyMap[int, string]{},
+	} {
+		for i := 0; i < 1000; i++ {
+			m.Store(i, strconv.Itoa(i))
+		}
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					m.Load(i % 1000)
+				}
+			})
+		})
+	}
+}
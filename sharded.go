@@ -0,0 +1,352 @@
+package ordered
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ShardedMap is an ordered map data structure that is safe for concurrent use
+// by multiple goroutines without additional locking or coordination. Keys are
+// spread across independently locked shards so that goroutines working on
+// different keys rarely contend.
+//
+// Load, LoadOrStore, LoadAndDelete, Delete, Store and StoreFirst only lock the
+// shard holding the key. The order is kept by giving each key a global
+// sequence number when it is added, Index, Range and String merge the shards
+// by sequence number and are O(n log n).
+//
+// The zero ShardedMap is empty and ready for use, it has four shards per CPU and
+// hashes keys with hash/maphash. A ShardedMap must not be copied after first
+// use.
+type ShardedMap[K comparable, V any] struct {
+	shards []shard[K, V]
+	hash   func(K) uint64
+	once   sync.Once
+
+	// first and last are the sequence numbers most recently given to keys
+	// added to the beginning and end.
+	first, last atomic.Int64
+}
+
+type shard[K comparable, V any] struct {
+	dirty map[K]sequenced[V]
+	mu    sync.RWMutex
+
+	// Pad shards apart to prevent false sharing between their locks.
+	_ [64]byte
+}
+
+type sequenced[V any] struct {
+	seq   int64
+	value V
+}
+
+// NewShardedMap returns an empty ShardedMap with n shards which uses hash to
+// pick the shard for each key. If n is less than one the default number of
+// shards is used, if hash is nil hash/maphash is used.
+func NewShardedMap[K comparable, V any](n int, hash func(K) uint64) *ShardedMap[K, V] {
+	m := &ShardedMap[K, V]{}
+	m.once.Do(func() {
+		m.init(n, hash)
+	})
+	return m
+}
+
+func (m *ShardedMap[K, V]) init(n int, hash func(K) uint64) {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0) * 4
+	}
+	if hash == nil {
+		seed := maphash.MakeSeed()
+		hash = func(key K) uint64 {
+			return hashKey(seed, key)
+		}
+	}
+
+	m.shards = make([]shard[K, V], n)
+	for i := range m.shards {
+		m.shards[i].dirty = make(map[K]sequenced[V])
+	}
+	m.hash = hash
+}
+
+// hashKey hashes key with seed, equal keys always have equal hashes. Strings,
+// numbers and pointers are hashed without allocating, other keys are walked
+// with reflect.
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return hashUint(seed, uint64(k))
+	case int8:
+		return hashUint(seed, uint64(k))
+	case int16:
+		return hashUint(seed, uint64(k))
+	case int32:
+		return hashUint(seed, uint64(k))
+	case int64:
+		return hashUint(seed, uint64(k))
+	case uint:
+		return hashUint(seed, uint64(k))
+	case uint8:
+		return hashUint(seed, uint64(k))
+	case uint16:
+		return hashUint(seed, uint64(k))
+	case uint32:
+		return hashUint(seed, uint64(k))
+	case uint64:
+		return hashUint(seed, k)
+	case uintptr:
+		return hashUint(seed, uint64(k))
+	case float32:
+		return hashUint(seed, floatBits(float64(k)))
+	case float64:
+		return hashUint(seed, floatBits(k))
+	}
+
+	v := reflect.ValueOf(key)
+	if k := v.Kind(); k == reflect.Pointer || k == reflect.UnsafePointer || k == reflect.Chan {
+		return hashUint(seed, uint64(v.Pointer()))
+	}
+	var h maphash.Hash
+	h.SetSeed(seed)
+	writeHash(&h, v)
+	return h.Sum64()
+}
+
+// hashUint hashes u with seed.
+func hashUint(seed maphash.Seed, u uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+	return maphash.Bytes(seed, buf[:])
+}
+
+// floatBits returns the bits of f with -0 written as 0, as they are equal keys.
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return math.Float64bits(f)
+}
+
+// writeHash writes the comparable value v to h. Floats are written so that 0
+// and -0, which are equal keys, have the same hash.
+func writeHash(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	writeUint := func(u uint64) {
+		binary.LittleEndian.PutUint64(buf[:], u)
+		_, _ = h.Write(buf[:])
+	}
+	writeFloat := func(f float64) {
+		writeUint(floatBits(f))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat(real(c))
+		writeFloat(imag(c))
+	case reflect.String:
+		_, _ = h.WriteString(v.String())
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Interface:
+		if !v.IsNil() {
+			writeHash(h, v.Elem())
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeHash(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			writeHash(h, v.Field(i))
+		}
+	}
+}
+
+// shard returns the shard holding key.
+func (m *ShardedMap[K, V]) shard(key K) *shard[K, V] {
+	m.once.Do(func() {
+		m.init(0, nil)
+	})
+	return &m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// Delete deletes the value for a key.
+func (m *ShardedMap[K, V]) Delete(key K) {
+	m.LoadAndDelete(key)
+}
+
+// Index loads the key and value of the key at index n. The loaded result
+// reports whether the index was in range. Negative value of n index from the
+// end of the ShardedMap.
+func (m *ShardedMap[K, V]) Index(n int) (key K, value V, loaded bool) {
+	keys, values := m.snapshot()
+	if n < 0 {
+		n += len(keys)
+	}
+	if n < 0 || n >= len(keys) {
+		return
+	}
+	return keys[n], values[n], true
+}
+
+// Len returns the number of keys in the ShardedMap.
+func (m *ShardedMap[K, V]) Len() int {
+	m.once.Do(func() {
+		m.init(0, nil)
+	})
+
+	var n int
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		n += len(s.dirty)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Load returns the value stored in the map for a key, or nil if no value is
+// present. The ok result indicates whether value was found in the map.
+func (m *ShardedMap[K, V]) Load(key K) (value V, ok bool) {
+	s := m.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.dirty[key]
+	return e.value, ok
+}
+
+// LoadAndDelete deletes the value for a key, returning the previous value if
+// any. The loaded result reports whether the key was present.
+func (m *ShardedMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, loaded := s.dirty[key]
+	delete(s.dirty, key)
+	return e.value, loaded
+}
+
+// LoadOrStore returns the existing value for the key if present. Otherwise, it
+// stores and returns the given value, adding it to the end. The loaded result
+// is true if the value was loaded, false if stored.
+func (m *ShardedMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.dirty[key]; ok {
+		return e.value, true
+	}
+	s.dirty[key] = sequenced[V]{seq: m.last.Add(1), value: value}
+	return value, false
+}
+
+// Range calls f sequentially for each key and value present in the map. If f
+// returns false, range stops the iteration.
+//
+// Range iterates over a copy of the ShardedMap's contents taken one shard at a
+// time, it does not correspond to any consistent snapshot of the whole map
+// when keys are stored or deleted concurrently. f may call any method on m.
+func (m *ShardedMap[K, V]) Range(f func(index int, key K, value V) bool) {
+	keys, values := m.snapshot()
+	for index := range keys {
+		if !f(index, keys[index], values[index]) {
+			return
+		}
+	}
+}
+
+// snapshot copies the contents of each shard in turn and merges them by
+// sequence number.
+func (m *ShardedMap[K, V]) snapshot() (keys []K, values []V) {
+	m.once.Do(func() {
+		m.init(0, nil)
+	})
+
+	type row struct {
+		key K
+		sequenced[V]
+	}
+	var rows []row
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		for key, e := range s.dirty {
+			rows = append(rows, row{key, e})
+		}
+		s.mu.RUnlock()
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].seq < rows[j].seq
+	})
+
+	keys = make([]K, len(rows))
+	values = make([]V, len(rows))
+	for i, r := range rows {
+		keys[i], values[i] = r.key, r.value
+	}
+	return
+}
+
+// Store sets the value for a key adding it to the end if it was not in the map.
+func (m *ShardedMap[K, V]) Store(key K, value V) {
+	m.store(key, value, &m.last, 1)
+}
+
+// StoreFirst sets the value for a key adding it to the beginning if it was not
+// in the map.
+func (m *ShardedMap[K, V]) StoreFirst(key K, value V) {
+	m.store(key, value, &m.first, -1)
+}
+
+// store sets the value for a key, if it was not in the map it is given the
+// next sequence number from seq.
+func (m *ShardedMap[K, V]) store(key K, value V, seq *atomic.Int64, delta int64) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.dirty[key]
+	if !ok {
+		e.seq = seq.Add(delta)
+	}
+	e.value = value
+	s.dirty[key] = e
+}
+
+// String formats the map for printing
+func (m *ShardedMap[K, V]) String() string {
+	keys, values := m.snapshot()
+	s := typeName(m) + "["
+	var space string
+	for i := range keys {
+		s += space + fmt.Sprint(keys[i]) + ":" + fmt.Sprint(values[i])
+		space = " "
+	}
+	return s + "]"
+}
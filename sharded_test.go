@@ -0,0 +1,283 @@
+package ordered
+
+import (
+	"hash/maphash"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestShardedMapOrder(t *testing.T) {
+	for name, test := range map[string]struct {
+		shards    int
+		hash      func(string) uint64
+		store     []string
+		storeFist []string
+		delete    []string
+		wantOrder []string
+	}{
+		"empty": {
+			wantOrder: []string{},
+		},
+		"store": {
+			store:     []string{"one", "two", "three", "four", "five"},
+			wantOrder: []string{"one", "two", "three", "four", "five"},
+		},
+		"store_first": {
+			store:     []string{"three", "four"},
+			storeFist: []string{"two", "one"},
+			wantOrder: []string{"one", "two", "three", "four"},
+		},
+		"store_twice": {
+			store:     []string{"one", "two", "three", "one"},
+			wantOrder: []string{"one", "two", "three"},
+		},
+		"delete": {
+			store:     []string{"one", "two", "three", "four"},
+			delete:    []string{"two", "notakey"},
+			wantOrder: []string{"one", "three", "four"},
+		},
+		"one_shard": {
+			shards:    1,
+			store:     []string{"one", "two", "three"},
+			wantOrder: []string{"one", "two", "three"},
+		},
+		"custom_hash": {
+			shards: 3,
+			hash: func(key string) uint64 {
+				return uint64(len(key))
+			},
+			store:     []string{"one", "two", "three", "four"},
+			wantOrder: []string{"one", "two", "three", "four"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := NewShardedMap[string, int](test.shards, test.hash)
+			for i, key := range test.store {
+				m.Store(key, i)
+			}
+			for i, key := range test.storeFist {
+				m.StoreFirst(key, i)
+			}
+			for _, key := range test.delete {
+				m.Delete(key)
+			}
+
+			order := []string{}
+			m.Range(func(index int, key string, value int) bool {
+				if index != len(order) {
+					t.Errorf("Unexpected index, wanted %d but got %d", len(order), index)
+				}
+				order = append(order, key)
+				return true
+			})
+			if !reflect.DeepEqual(order, test.wantOrder) {
+				t.Errorf("Unexpected order content\nactual: %#v\nwant  : %#v", order, test.wantOrder)
+			}
+			if m.Len() != len(test.wantOrder) {
+				t.Errorf("Unexpected length, wanted %d but got %d", len(test.wantOrder), m.Len())
+			}
+			for i, want := range test.wantOrder {
+				if key, _, loaded := m.Index(i); key != want || !loaded {
+					t.Errorf("Unexpected key at index %d, wanted %q but got %q", i, want, key)
+				}
+			}
+		})
+	}
+}
+
+// TestShardedMapDefaultHash checks that equal keys of each kind are found in
+// the same shard by the default hash.
+func TestShardedMapDefaultHash(t *testing.T) {
+	type point struct {
+		x, y float64
+		name string
+	}
+	one, zero, negZero := 1, 0.0, math.Copysign(0, -1)
+	for name, check := range map[string]func(t *testing.T){
+		"int":     func(t *testing.T) { checkSameShard(t, 1, 1) },
+		"string":  func(t *testing.T) { checkSameShard(t, "one", "one") },
+		"pointer": func(t *testing.T) { checkSameShard(t, &one, &one) },
+		"zero":    func(t *testing.T) { checkSameShard(t, zero, negZero) },
+		"struct":  func(t *testing.T) { checkSameShard(t, point{zero, 1, "a"}, point{negZero, 1, "a"}) },
+		"array":   func(t *testing.T) { checkSameShard(t, [2]float64{zero, 1}, [2]float64{negZero, 1}) },
+		"interface": func(t *testing.T) {
+			seed := maphash.MakeSeed()
+			hash := func(key any) uint64 {
+				var h maphash.Hash
+				h.SetSeed(seed)
+				writeHash(&h, reflect.ValueOf(key))
+				return h.Sum64()
+			}
+			if hash([1]any{zero}) != hash([1]any{negZero}) {
+				t.Error("Equal keys have different hashes")
+			}
+		},
+	} {
+		t.Run(name, check)
+	}
+}
+
+// checkSameShard checks that load is found in a ShardedMap after store is
+// stored.
+func checkSameShard[K comparable](t *testing.T, store, load K) {
+	t.Helper()
+	m := NewShardedMap[K, int](64, nil)
+	m.Store(store, 1)
+	if _, ok := m.Load(load); !ok {
+		t.Errorf("Key %#v not loaded", load)
+	}
+}
+
+func TestShardedMapHashAllocs(t *testing.T) {
+	seed := maphash.MakeSeed()
+	one := 1
+	for name, hash := range map[string]func(){
+		"string":  func() { hashKey(seed, "one") },
+		"int":     func() { hashKey(seed, 1<<40) },
+		"uint8":   func() { hashKey(seed, uint8(1)) },
+		"float":   func() { hashKey(seed, 1.5) },
+		"pointer": func() { hashKey(seed, &one) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, hash); allocs != 0 {
+				t.Errorf("Unexpected allocations, wanted 0 but got %v", allocs)
+			}
+		})
+	}
+}
+
+func TestShardedMapIndex(t *testing.T) {
+	var m ShardedMap[string, int]
+	for i, key := range []string{"zero", "one", "two"} {
+		m.Store(key, i)
+	}
+	for name, test := range map[string]struct {
+		index      int
+		wantKey    string
+		wantValue  int
+		wantLoaded bool
+	}{
+		"index_zero":                   {index: 0, wantKey: "zero", wantValue: 0, wantLoaded: true},
+		"index_two":                    {index: 2, wantKey: "two", wantValue: 2, wantLoaded: true},
+		"index_last":                   {index: -1, wantKey: "two", wantValue: 2, wantLoaded: true},
+		"index_out_of_range":           {index: 3},
+		"index_out_of_range_backwards": {index: -4},
+	} {
+		t.Run(name, func(t *testing.T) {
+			key, value, loaded := m.Index(test.index)
+			if key != test.wantKey {
+				t.Errorf("Unexpected key, wanted %q but got %q", test.wantKey, key)
+			}
+			if value != test.wantValue {
+				t.Errorf("Unexpected value, wanted %d but got %d", test.wantValue, value)
+			}
+			if loaded != test.wantLoaded {
+				t.Errorf("Unexpected OK, wanted %t but got %t", test.wantLoaded, loaded)
+			}
+		})
+	}
+}
+
+func TestShardedMapLoad(t *testing.T) {
+	var m ShardedMap[string, int]
+	if _, ok := m.Load("one"); ok {
+		t.Error("Loaded a key from an empty map")
+	}
+
+	if actual, loaded := m.LoadOrStore("one", 1); actual != 1 || loaded {
+		t.Errorf("Unexpected LoadOrStore, wanted 1 false but got %d %t", actual, loaded)
+	}
+	if actual, loaded := m.LoadOrStore("one", 2); actual != 1 || !loaded {
+		t.Errorf("Unexpected LoadOrStore, wanted 1 true but got %d %t", actual, loaded)
+	}
+	if value, ok := m.Load("one"); value != 1 || !ok {
+		t.Errorf("Unexpected Load, wanted 1 true but got %d %t", value, ok)
+	}
+	if value, loaded := m.LoadAndDelete("one"); value != 1 || !loaded {
+		t.Errorf("Unexpected LoadAndDelete, wanted 1 true but got %d %t", value, loaded)
+	}
+	if value, loaded := m.LoadAndDelete("one"); value != 0 || loaded {
+		t.Errorf("Unexpected LoadAndDelete, wanted 0 false but got %d %t", value, loaded)
+	}
+}
+
+func TestShardedMapString(t *testing.T) {
+	var m ShardedMap[string, int]
+	m.Store("seven", 7)
+	m.Store("nine", 9)
+	m.StoreFirst("one", 1)
+	want := "github.com/brackendawson/ordered.ShardedMap[string,int][one:1 seven:7 nine:9]"
+	if got := m.String(); got != want {
+		t.Errorf("Not equal:\n\twant: %s\n\tgot : %s", want, got)
+	}
+}
+
+// TestShardedMapConcurrent is most useful with the race detector.
+func TestShardedMapConcurrent(t *testing.T) {
+	var (
+		m  ShardedMap[int, int]
+		wg sync.WaitGroup
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := w*1000 + i
+				m.Store(key, i)
+				if i%3 == 0 {
+					m.Delete(key)
+				}
+				if i%100 == 0 {
+					m.Range(func(int, int, int) bool { return true })
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if want := 8 * 666; m.Len() != want {
+		t.Errorf("Unexpected length, wanted %d but got %d", want, m.Len())
+	}
+	last := -1
+	m.Range(func(index int, key, value int) bool {
+		if w := key / 1000; key%1000 <= last%1000 && w == last/1000 {
+			t.Errorf("Key %d out of order after %d", key, last)
+		}
+		last = key
+		return true
+	})
+}
+
+// BenchmarkContention loads and stores from many goroutines, storing one time
+// in ten.
+func BenchmarkContention(b *testing.B) {
+	type loadStorer interface {
+		Load(int) (string, bool)
+		Store(int, string)
+	}
+	for name, m := range map[string]loadStorer{
+		"Map":        &Map[int, string]{},
+		"ShardedMap": &ShardedMap[int, string]{},
+	} {
+		for i := 0; i < 1000; i++ {
+			m.Store(i, strconv.Itoa(i))
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetParallelism(64)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if i%10 == 0 {
+						m.Store(i%1000, "")
+						continue
+					}
+					m.Load(i % 1000)
+				}
+			})
+		})
+	}
+}
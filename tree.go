@@ -0,0 +1,177 @@
+package ordered
+
+// tree is a treap backing keyed implicitly by index. Each entry's prev and next
+// fields are its left and right children, pos is the size of its subtree and
+// prio is a random heap priority which keeps the tree balanced in expectation.
+type tree[K comparable, V any] struct {
+	root *entry[K, V]
+	seed uint32
+}
+
+// size returns the number of entries in the subtree rooted at e.
+func size[K comparable, V any](e *entry[K, V]) int {
+	if e == nil {
+		return 0
+	}
+	return e.pos
+}
+
+// update recalculates the size of e and adopts its children.
+func update[K comparable, V any](e *entry[K, V]) {
+	e.pos = 1 + size(e.prev) + size(e.next)
+	if e.prev != nil {
+		e.prev.parent = e
+	}
+	if e.next != nil {
+		e.next.parent = e
+	}
+}
+
+// split splits the subtree rooted at e into the first n entries and the rest.
+func split[K comparable, V any](e *entry[K, V], n int) (l, r *entry[K, V]) {
+	if e == nil {
+		return nil, nil
+	}
+	if size(e.prev) < n {
+		e.next, r = split(e.next, n-size(e.prev)-1)
+		update(e)
+		return e, r
+	}
+	l, e.prev = split(e.prev, n)
+	update(e)
+	return l, e
+}
+
+// merge joins the subtrees rooted at l and r, every entry in l is before every
+// entry in r.
+func merge[K comparable, V any](l, r *entry[K, V]) *entry[K, V] {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.prio > r.prio {
+		l.next = merge(l.next, r)
+		update(l)
+		return l
+	}
+	r.prev = merge(l, r.prev)
+	update(r)
+	return r
+}
+
+// rand returns the next pseudo-random priority using xorshift.
+func (t *tree[K, V]) rand() uint32 {
+	t.seed ^= t.seed << 13
+	t.seed ^= t.seed >> 17
+	t.seed ^= t.seed << 5
+	return t.seed
+}
+
+func (t *tree[K, V]) len() int {
+	return size(t.root)
+}
+
+func (t *tree[K, V]) at(n int) *entry[K, V] {
+	e := t.root
+	for {
+		switch l := size(e.prev); {
+		case n < l:
+			e = e.prev
+		case n > l:
+			n -= l + 1
+			e = e.next
+		default:
+			return e
+		}
+	}
+}
+
+// index counts the entries before e by walking up to the root, adding the left
+// subtree of each ancestor that e is to the right of.
+func (t *tree[K, V]) index(e *entry[K, V]) int {
+	n := size(e.prev)
+	for ; e.parent != nil; e = e.parent {
+		if e.parent.next == e {
+			n += size(e.parent.prev) + 1
+		}
+	}
+	return n
+}
+
+func (t *tree[K, V]) insert(n int, e *entry[K, V]) {
+	e.prev, e.next = nil, nil
+	e.pos = 1
+	e.prio = t.rand()
+
+	l, r := split(t.root, n)
+	t.root = merge(merge(l, e), r)
+	t.root.parent = nil
+}
+
+// remove replaces e with the merge of its children and shrinks its ancestors.
+func (t *tree[K, V]) remove(e *entry[K, V]) {
+	c := merge(e.prev, e.next)
+	p := e.parent
+	if c != nil {
+		c.parent = p
+	}
+	switch {
+	case p == nil:
+		t.root = c
+	case p.prev == e:
+		p.prev = c
+	default:
+		p.next = c
+	}
+	for ; p != nil; p = p.parent {
+		p.pos--
+	}
+
+	e.prev, e.next, e.parent = nil, nil, nil
+}
+
+// removeRange splits out the entries from i to j and merges the rest.
+func (t *tree[K, V]) removeRange(i, j int) {
+	l, r := split(t.root, i)
+	_, r = split(r, j-i)
+	t.root = merge(l, r)
+	if t.root != nil {
+		t.root.parent = nil
+	}
+}
+
+// grow does nothing, a tree allocates each entry as it is inserted.
+func (t *tree[K, V]) grow(int) {}
+
+// compact does nothing, a tree holds no spare capacity.
+func (t *tree[K, V]) compact() {}
+
+func (t *tree[K, V]) next(e *entry[K, V]) *entry[K, V] {
+	if e.next != nil {
+		e = e.next
+		for e.prev != nil {
+			e = e.prev
+		}
+		return e
+	}
+	for e.parent != nil && e.parent.next == e {
+		e = e.parent
+	}
+	return e.parent
+}
+
+func (t *tree[K, V]) prev(e *entry[K, V]) *entry[K, V] {
+	if e.prev != nil {
+		e = e.prev
+		for e.next != nil {
+			e = e.next
+		}
+		return e
+	}
+	for e.parent != nil && e.parent.prev == e {
+		e = e.parent
+	}
+	return e.parent
+}
@@ -0,0 +1,85 @@
+package ordered
+
+import "sync"
+
+// View is a read-only window onto a range of keys in a Map or LocalMap which
+// does not copy them. The bounds of the range are resolved against the map
+// each time the View is used, so a View follows the map as keys are stored and
+// deleted.
+//
+// A View of a Map is safe for concurrent use, each method takes the Map's read
+// lock. A View of a LocalMap is not.
+type View[K comparable, V any] struct {
+	local *LocalMap[K, V]
+	mu    *sync.RWMutex
+	i, j  int
+}
+
+func (v *View[K, V]) rlock() {
+	if v.mu != nil {
+		v.mu.RLock()
+	}
+}
+
+func (v *View[K, V]) runlock() {
+	if v.mu != nil {
+		v.mu.RUnlock()
+	}
+}
+
+// bounds returns the range of indices in the map currently covered by v.
+func (v *View[K, V]) bounds() (i, j int) {
+	return span(v.i, v.j, v.local.len())
+}
+
+// Index loads the key and value of the key at index n of the View. The loaded
+// result reports whether the index was in range. Negative value of n index
+// from the end of the View.
+func (v *View[K, V]) Index(n int) (key K, value V, loaded bool) {
+	v.rlock()
+	defer v.runlock()
+
+	i, j := v.bounds()
+	if n < 0 {
+		n += j - i
+	}
+	if n < 0 || n >= j-i {
+		return
+	}
+	e := v.local.order.at(i + n)
+	return e.key, e.value, true
+}
+
+// Len returns the number of keys in the View.
+func (v *View[K, V]) Len() int {
+	v.rlock()
+	defer v.runlock()
+
+	i, j := v.bounds()
+	return j - i
+}
+
+// Range calls f sequentially for each key and value in the View. If f returns
+// false, range stops the iteration. Range has the same consistency as
+// Map.Range, f may call any method on the map or the View.
+func (v *View[K, V]) Range(f func(index int, key K, value V) bool) {
+	var (
+		e   *entry[K, V]
+		gen uint64
+	)
+	for index := 0; ; index++ {
+		v.rlock()
+		i, j := v.bounds()
+		if i+index >= j {
+			v.runlock()
+			return
+		}
+		e, gen = v.local.seek(e, gen, i+index)
+		key, value := e.key, e.value
+		v.runlock()
+
+		if !f(index, key, value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ordered
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestViewIndex(t *testing.T) {
+	for name, test := range map[string]struct {
+		i, j       int
+		n          int
+		wantKey    string
+		wantLoaded bool
+	}{
+		"first":                 {i: 1, j: 3, n: 0, wantKey: "one", wantLoaded: true},
+		"last":                  {i: 1, j: 3, n: -1, wantKey: "two", wantLoaded: true},
+		"out_of_range":          {i: 1, j: 3, n: 2},
+		"negative_out_of_range": {i: 1, j: 3, n: -3},
+		"negative_bounds":       {i: -2, j: -1, n: 0, wantKey: "two", wantLoaded: true},
+		"clamped_bounds":        {i: -10, j: 10, n: 3, wantKey: "three", wantLoaded: true},
+		"empty":                 {i: 3, j: 1, n: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap[string, int]()
+			fill(&m.local, []string{"zero", "one", "two", "three"}, map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3})
+			key, _, loaded := m.View(test.i, test.j).Index(test.n)
+			if key != test.wantKey || loaded != test.wantLoaded {
+				t.Errorf("Unexpected result, wanted %q, %t but got %q, %t", test.wantKey, test.wantLoaded, key, loaded)
+			}
+		})
+	}
+}
+
+// TestViewFollows checks that a View sees changes made to its map.
+func TestViewFollows(t *testing.T) {
+	l := NewLocalMap[string, int]()
+	fill(l, []string{"zero", "one", "two", "three"}, map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3})
+	v := l.View(-2, 10)
+	if v.Len() != 2 {
+		t.Errorf("Unexpected length, wanted 2 but got %d", v.Len())
+	}
+
+	l.Store("four", 4)
+	l.Delete("zero")
+	var got []string
+	v.Range(func(index int, key string, value int) bool {
+		got = append(got, key)
+		return true
+	})
+	if want := []string{"three", "four"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got unexpected keys\nactual: %#v\nwant  : %#v", got, want)
+	}
+}
+
+func TestViewRange(t *testing.T) {
+	for name, b := range backings {
+		t.Run(name, func(t *testing.T) {
+			m := NewMap[string, int](WithBacking(b))
+			fill(&m.local, []string{"zero", "one", "two", "three", "four"}, map[string]int{"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4})
+
+			var got []string
+			m.View(1, -1).Range(func(index int, key string, value int) bool {
+				got = append(got, key)
+				if key == "one" {
+					m.Delete("zero")
+				}
+				return true
+			})
+			if want := []string{"one", "three"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Got unexpected keys\nactual: %#v\nwant  : %#v", got, want)
+			}
+
+			got = nil
+			m.View(0, 3).Range(func(index int, key string, value int) bool {
+				got = append(got, key)
+				return index < 1
+			})
+			if want := []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Got unexpected keys\nactual: %#v\nwant  : %#v", got, want)
+			}
+		})
+	}
+}